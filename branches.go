@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"schema-manager/schemamanager"
+)
+
+// status --branches 要比较的远程分支
+var branchList []string
+
+type branchJSON struct {
+	Branch     string `json:"branch" yaml:"branch"`
+	RemoteHash string `json:"remoteHash,omitempty" yaml:"remoteHash,omitempty"`
+	State      string `json:"state" yaml:"state"`
+	Behind     int    `json:"behind" yaml:"behind"`
+	Ahead      int    `json:"ahead" yaml:"ahead"`
+}
+
+// status --branches：用一次远程查询比较本地 HEAD 和多个远程分支，输出一张表；
+// 任一分支和 HEAD 不一致时退出码为 4，--check 时只设置退出码
+func checkBranches() error {
+	if localDir != "" {
+		if checkOnly {
+			return nil
+		}
+		return errors.New("version control information is not available for a local directory")
+	}
+	if branch != "" {
+		return errors.New("--branches cannot be combined with --branch")
+	}
+	var names []string
+	for _, b := range branchList {
+		if b = strings.TrimSpace(b); b != "" {
+			names = append(names, b)
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("--branches needs at least one branch name")
+	}
+
+	ctx, cancel := networkContext()
+	defer cancel()
+	m := newManager()
+	if checkOnly {
+		m.Warnings, m.Verbose = nil, nil
+	}
+	head, result, err := m.BranchesStatus(ctx, names)
+	if err != nil {
+		return timeoutError(ctx, err)
+	}
+	synced := true
+	for _, s := range result {
+		if s.State != schemamanager.BranchUpToDate {
+			synced = false
+		}
+	}
+	if checkOnly {
+		if !synced {
+			return &exitError{code: exitBehind}
+		}
+		return nil
+	}
+
+	if structured() {
+		out := make([]branchJSON, len(result))
+		for i, s := range result {
+			out[i] = branchJSON{Branch: s.Branch, State: string(s.State), Behind: s.Behind, Ahead: s.Ahead}
+			if !s.RemoteHash.IsZero() {
+				out[i].RemoteHash = s.RemoteHash.String()
+			}
+		}
+		if err := writeStructured(out); err != nil {
+			return err
+		}
+	} else {
+		printBranches(head.String()[:8], result)
+	}
+	if !synced {
+		return &exitError{code: exitBehind}
+	}
+	return nil
+}
+
+func printBranches(head string, result []schemamanager.BranchStatus) {
+	width := len("BRANCH")
+	for _, s := range result {
+		width = max(width, len(s.Branch))
+	}
+	infof("Local HEAD: %s\n", head)
+	infof("%-*s  %-8s  %s\n", width, "BRANCH", "REMOTE", "STATE")
+	for _, s := range result {
+		remote := "-"
+		if !s.RemoteHash.IsZero() {
+			remote = s.RemoteHash.String()[:8]
+		}
+		infof("%-*s  %-8s  %s\n", width, s.Branch, remote, branchState(s))
+	}
+}
+
+// 状态列，先后关系已知时附上提交数
+func branchState(s schemamanager.BranchStatus) string {
+	switch s.State {
+	case schemamanager.BranchUpToDate:
+		return paint(ansiGreen, "up to date")
+	case schemamanager.BranchBehind:
+		return paint(ansiRed, fmt.Sprintf("behind by %d", s.Behind))
+	case schemamanager.BranchAhead:
+		return paint(ansiYellow, fmt.Sprintf("ahead by %d", s.Ahead))
+	case schemamanager.BranchDiverged:
+		return paint(ansiRed, fmt.Sprintf("diverged (%d behind, %d ahead)", s.Behind, s.Ahead))
+	case schemamanager.BranchUnknown:
+		return paint(ansiRed, "differs (remote commits are not available locally)")
+	default:
+		return paint(ansiRed, "missing on remote")
+	}
+}
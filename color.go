@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ANSI 颜色代码
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[36m"
+	ansiMatch  = "\x1b[1;31m"
+)
+
+// 是否输出颜色，由 resolveColor 根据 --color、NO_COLOR 和终端检测决定
+var useColor bool
+
+// 解析 --color；JSON 输出永远不带颜色
+func resolveColor() error {
+	if noColor {
+		colorMode = "never"
+	}
+	switch colorMode {
+	case "always":
+		useColor = true
+	case "never":
+		useColor = false
+	case "auto":
+		_, set := os.LookupEnv("NO_COLOR")
+		useColor = !set && isTerminal(os.Stdout)
+	default:
+		return fmt.Errorf("invalid color mode %q: must be auto, always or never", colorMode)
+	}
+	if structured() {
+		useColor = false
+	}
+	return nil
+}
+
+func paint(code, s string) string {
+	if !useColor || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// 高亮 text 中所有匹配 regex 的部分
+func highlight(text string, regex *regexp.Regexp) string {
+	if !useColor || regex == nil {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range regex.FindAllStringIndex(text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(paint(ansiMatch, text[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
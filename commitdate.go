@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"schema-manager/schemamanager"
+)
+
+// list --by-commit-date：按最后修改文件的提交时间排序，而不是文件系统的修改时间
+var byCommit bool
+
+// 带有最后修改提交的文件，JSON 中和 File 的字段平铺在一起
+type committedFile struct {
+	schemamanager.File
+	Commit *schemamanager.LastCommit `json:"commit,omitempty"`
+}
+
+// 按最后修改的提交时间从旧到新列出文件，--reverse 时从新到旧；没有提交记录的文件排在最后
+func listByCommitDate(files []schemamanager.File) error {
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--since", since != ""},
+		{"--dirs-only", dirsOnly},
+		{"--files-only", filesOnly},
+		{"--format", listFormat != ""},
+		{"--group-by", grouped()},
+		{"--sort " + sortKey, sortKey != "path"},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("--by-commit-date cannot be combined with %s", c.flag)
+		}
+	}
+	if err := requireGit("list --by-commit-date"); err != nil {
+		return err
+	}
+
+	commits, err := newManager().LastCommits(files)
+	if err != nil {
+		return err
+	}
+	list := make([]committedFile, len(files))
+	for i, f := range files {
+		list[i].File = f
+		if c, ok := commits[f.Path]; ok {
+			list[i].Commit = &c
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Commit, list[j].Commit
+		if a == nil || b == nil {
+			return a != nil
+		}
+		if reverse {
+			return a.When.After(b.When)
+		}
+		return a.When.Before(b.When)
+	})
+
+	if structured() {
+		return writeStructured(list)
+	}
+
+	fmt.Println("Listing .hl files by last commit date:")
+	fmt.Println("=====================================")
+	for _, f := range list {
+		if f.Commit == nil {
+			fmt.Printf("  %-10s  %-8s  %s\n", "-", "-", f.Path)
+			continue
+		}
+		fmt.Printf("  %s  %s  %s\n", f.Commit.When.Local().Format(time.DateOnly), paint(ansiYellow, f.Commit.Hash[:8]), f.Path)
+	}
+	if n := len(files) - len(commits); n > 0 {
+		fmt.Printf("Found %d .hl files, %d not committed\n", len(files), n)
+	} else {
+		fmt.Printf("Found %d .hl files\n", len(files))
+	}
+	return nil
+}
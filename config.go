@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件 ~/.opencmd/config.yaml 中的默认值
+type fileConfig struct {
+	Repo     string `yaml:"repo,omitempty"`
+	CacheDir string `yaml:"cache-dir,omitempty"`
+	Branch   string `yaml:"branch,omitempty"`
+	Output   string `yaml:"output,omitempty"`
+	// Checksum 是 verify 默认期望的摘要
+	Checksum string `yaml:"checksum,omitempty"`
+	// Profile 是当前使用的仓库配置名，为空时使用 default
+	Profile  string             `yaml:"profile,omitempty"`
+	Profiles map[string]profile `yaml:"profiles,omitempty"`
+}
+
+// 一个具名的 schema 仓库，有自己的地址、分支和缓存目录
+type profile struct {
+	Repo     string `yaml:"repo"`
+	Branch   string `yaml:"branch,omitempty"`
+	CacheDir string `yaml:"cache-dir,omitempty"`
+	Checksum string `yaml:"checksum,omitempty"`
+}
+
+// 配置文件中当前仓库的 checksum，verify 的 --expect 优先
+var expectedChecksum string
+
+// 配置项名称到字段的映射
+func (c *fileConfig) fields() map[string]*string {
+	return map[string]*string{
+		"repo":      &c.Repo,
+		"cache-dir": &c.CacheDir,
+		"branch":    &c.Branch,
+		"output":    &c.Output,
+		"checksum":  &c.Checksum,
+	}
+}
+
+func (c *fileConfig) keys() []string {
+	var keys []string
+	for k := range c.fields() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 配置文件路径，可以用 OPENCMD_CONFIG 覆盖
+func configPath() (string, error) {
+	if env := os.Getenv("OPENCMD_CONFIG"); env != "" {
+		return env, nil
+	}
+	base, err := opencmdDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "config.yaml"), nil
+}
+
+// 读取配置文件，文件不存在时返回空配置
+func loadConfig() (*fileConfig, error) {
+	cfg := &fileConfig{}
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return cfg, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func saveConfig(cfg *fileConfig) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// 解析全局设置，优先级：命令行参数 > 环境变量 > 配置文件 > 内置默认值
+func resolveSettings(cmd *cobra.Command) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// default 使用配置文件顶层的值，其他仓库配置使用自己的值
+	resolveString(cmd, "profile", "OPENCMD_PROFILE", cfg.Profile, &activeProfile)
+	repoValue, branchValue, cacheValue := cfg.Repo, cfg.Branch, cfg.CacheDir
+	expectedChecksum = cfg.Checksum
+	if activeProfile != defaultProfile {
+		p, ok := cfg.Profiles[activeProfile]
+		if !ok {
+			return fmt.Errorf("unknown profile %q; see 'schema-manager repo list'", activeProfile)
+		}
+		repoValue, branchValue, cacheValue = p.Repo, p.Branch, p.CacheDir
+		expectedChecksum = p.Checksum
+		if cacheValue == "" {
+			base, err := opencmdDir()
+			if err != nil {
+				return err
+			}
+			cacheValue = profileCacheDir(base, activeProfile)
+		}
+	}
+
+	resolveString(cmd, "repo", "OPENCMD_REPO", repoValue, &repoURL)
+	resolveString(cmd, "token", "OPENCMD_TOKEN", "", &token)
+	resolveString(cmd, "branch", "", branchValue, &branch)
+	resolveString(cmd, "output", "", cfg.Output, &outputFmt)
+	resolveString(cmd, "cache-dir", "OPENCMD_CACHE_DIR", cacheValue, &cacheDir)
+
+	// 跳过证书校验只能由命令行或环境变量打开，不从配置文件读取，避免长期生效
+	if v := os.Getenv("OPENCMD_INSECURE"); v != "" && !cmd.Flags().Changed("insecure") {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid OPENCMD_INSECURE %q: must be true or false", v)
+		}
+		insecure = b
+	}
+
+	if cacheDir != "" {
+		if cacheDir, err = expandPath(cacheDir); err != nil {
+			return err
+		}
+	}
+	if cacheDir == "" {
+		base, err := opencmdDir()
+		if err != nil {
+			return err
+		}
+		cacheDir = profileCacheDir(base, defaultProfile)
+	}
+
+	// --local 直接读取已有目录，不需要 init
+	resolveString(cmd, "local", "OPENCMD_LOCAL", "", &localDir)
+	if localDir != "" {
+		if localDir, err = expandPath(localDir); err != nil {
+			return err
+		}
+		info, err := os.Stat(localDir)
+		if err != nil || !info.IsDir() {
+			return fmt.Errorf("local schema directory %q does not exist", localDir)
+		}
+		cacheDir = localDir
+	}
+
+	// 转成绝对路径，保证 filepath.Rel 的输出合理
+	abs, err := filepath.Abs(cacheDir)
+	if err != nil {
+		return fmt.Errorf("resolving cache directory: %w", err)
+	}
+	cacheDir = abs
+
+	// 缓存在 ~/.opencmd 的默认位置时才由 migrateLayout 管理，用户指定的目录保持原样
+	layoutBase = ""
+	if base, err := opencmdDir(); err == nil && localDir == "" && cacheDir == profileCacheDir(base, activeProfile) {
+		layoutBase = base
+	}
+	return checkCacheDir(cacheDir)
+}
+
+// 展开开头的 ~ 和路径中的环境变量，配置文件和环境变量里的值不会经过 shell 展开
+func expandPath(p string) (string, error) {
+	p = os.ExpandEnv(p)
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~"+string(filepath.Separator)) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("expanding %q: %w", p, err)
+		}
+		p = filepath.Join(homeDir, p[1:])
+	}
+	return p, nil
+}
+
+// 缓存目录要么是已有的目录，要么可以创建：最近的已存在的上级是目录
+func checkCacheDir(dir string) error {
+	for p := dir; ; p = filepath.Dir(p) {
+		info, err := os.Stat(p)
+		if err == nil {
+			if info.IsDir() {
+				return nil
+			}
+			if p == dir {
+				return fmt.Errorf("cache directory %s is not a directory", dir)
+			}
+			return fmt.Errorf("cache directory %s cannot be created: %s is not a directory", dir, p)
+		}
+		// 上级是文件时 stat 报告 ENOTDIR，继续向上找到那个文件
+		if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, syscall.ENOTDIR) || filepath.Dir(p) == p {
+			return fmt.Errorf("checking cache directory: %w", err)
+		}
+	}
+}
+
+// 用户的 ~/.opencmd 目录
+func opencmdDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".opencmd"), nil
+}
+
+// 仓库配置默认的缓存目录，每个配置一个子目录，索引文件也随之分开
+func profileCacheDir(base, name string) string {
+	return filepath.Join(base, "profiles", name, "commands")
+}
+
+// 参数未显式传入时依次使用环境变量和配置文件中的值
+func resolveString(cmd *cobra.Command, flag, env, fromConfig string, target *string) {
+	if cmd.Flags().Changed(flag) {
+		return
+	}
+	if env != "" {
+		if v := os.Getenv(env); v != "" {
+			*target = v
+			return
+		}
+	}
+	if fromConfig != "" {
+		*target = fromConfig
+	}
+}
+
+func newConfigCmd() *cobra.Command {
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Get or set persistent defaults in the config file",
+		Long: `Manage persistent defaults stored in ~/.opencmd/config.yaml (or $OPENCMD_CONFIG).
+
+Supported keys: repo, cache-dir, branch, output, checksum.
+
+Settings are resolved in this order, first match wins:
+  1. command-line flags (--repo, --cache-dir, --branch, --output)
+  2. environment variables (OPENCMD_REPO, OPENCMD_CACHE_DIR)
+  3. the active profile added with 'schema-manager repo add'
+  4. the config file
+  5. built-in defaults`,
+	}
+
+	var getCmd = &cobra.Command{
+		Use:   "get [key]",
+		Short: "Print a config value, or all values when no key is given",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				for _, k := range cfg.keys() {
+					fmt.Printf("%s = %s\n", k, *cfg.fields()[k])
+				}
+				return nil
+			}
+			v, ok := cfg.fields()[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown config key %q", args[0])
+			}
+			fmt.Println(*v)
+			return nil
+		},
+	}
+
+	var setCmd = &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a config value; an empty value removes it",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			v, ok := cfg.fields()[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown config key %q", args[0])
+			}
+			if args[0] == "output" && args[1] != "" {
+				if err := validOutputFormat(args[1]); err != nil {
+					return err
+				}
+			}
+			*v = args[1]
+			return saveConfig(cfg)
+		},
+	}
+
+	configCmd.AddCommand(getCmd, setCmd)
+	return configCmd
+}
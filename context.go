@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"schema-manager/schemamanager"
+)
+
+// search --context/-A/-B：匹配行前后附带的上下文行数
+var contextN, afterN, beforeN int
+
+// 把上下文行数填入 opts；-A 和 -B 优先于 --context，和 grep 一致
+func contextOptions(opts *schemamanager.SearchOptions) error {
+	if contextN < 0 || afterN < 0 || beforeN < 0 {
+		return errors.New("context line counts cannot be negative")
+	}
+	opts.Before, opts.After = contextN, contextN
+	if beforeN > 0 {
+		opts.Before = beforeN
+	}
+	if afterN > 0 {
+		opts.After = afterN
+	}
+	if opts.Before == 0 && opts.After == 0 {
+		return nil
+	}
+	if !searchBody {
+		return errors.New("--context, --after-context and --before-context require --content")
+	}
+	if withMatch || noMatch {
+		return errors.New("--context cannot be combined with --files-with-matches or --files-without-match")
+	}
+	return nil
+}
+
+// 按 grep 的格式输出匹配行及其上下文：匹配行是 path:line:，上下文行是 path-line-；
+// 相邻匹配的上下文重叠或相连时合并输出，不连续的组之间用 -- 分隔
+func printWithContext(matches []schemamanager.Match, regex *regexp.Regexp, prefix func(path string) string) {
+	lastPath, last := "", 0
+	for i, match := range matches {
+		same := i > 0 && match.Path == lastPath
+		start := match.Line - len(match.Before)
+		if i > 0 && (!same || start > last+1) {
+			fmt.Println(paint(ansiBlue, "--"))
+		}
+		for j, text := range match.Before {
+			if n := start + j; !same || n > last {
+				printContextLine(prefix(match.Path), match.Path, n, text)
+			}
+		}
+		printMatch(match, regex, prefix(match.Path))
+
+		// 之后的上下文在下一个匹配行之前结束，那一行由下一个匹配输出
+		after := len(match.After)
+		if i+1 < len(matches) && matches[i+1].Path == match.Path {
+			after = min(after, matches[i+1].Line-match.Line-1)
+		}
+		for j := 0; j < after; j++ {
+			printContextLine(prefix(match.Path), match.Path, match.Line+1+j, match.After[j])
+		}
+		lastPath, last = match.Path, match.Line+after
+	}
+}
+
+func printContextLine(prefix, path string, line int, text string) {
+	fmt.Printf("  %s%s-%s- %s\n", prefix, paint(ansiCyan, path), strconv.Itoa(line), text)
+}
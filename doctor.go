@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"schema-manager/schemamanager"
+)
+
+// doctor 的一项检查
+type check struct {
+	name string
+	// critical 的检查失败时 doctor 以非零状态退出
+	critical bool
+	run      func() (detail string, hint string, ok bool)
+}
+
+func runDoctor() error {
+	m := newManager()
+	checks := []check{
+		{"Home directory", true, func() (string, string, bool) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err.Error(), "set the HOME environment variable or pass --cache-dir", false
+			}
+			return home, "", true
+		}},
+		{"Cache directory writable", true, func() (string, string, bool) {
+			// 缓存目录不存在时检查它最近的已存在的上级目录
+			dir := cacheDir
+			for {
+				if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+					break
+				}
+				dir = filepath.Dir(dir)
+			}
+			f, err := os.CreateTemp(dir, ".doctor-*")
+			if err != nil {
+				return err.Error(), "fix the permissions of " + dir + " or choose another --cache-dir", false
+			}
+			f.Close()
+			os.Remove(f.Name())
+			return cacheDir, "", true
+		}},
+	}
+
+	if localDir == "" {
+		checks = append(checks,
+			check{"Cache repository", true, func() (string, string, bool) {
+				switch m.State() {
+				case schemamanager.CacheMissing:
+					return "not initialized", "run 'schema-manager init'", false
+				case schemamanager.CacheBroken:
+					return "present but cannot be opened", "run 'schema-manager init --repair'", false
+				}
+				return "ok", "", true
+			}},
+			// 离线时缓存仍然可以使用
+			check{"Remote reachable", false, func() (string, string, bool) {
+				ctx, cancel := networkContext()
+				defer cancel()
+				refs, err := m.RemoteRefs(ctx)
+				if err != nil {
+					return timeoutError(ctx, err).Error(), "check your network connection, --repo, and credentials (--token or ~/.ssh keys)", false
+				}
+				return fmt.Sprintf("%s (%d refs)", repoURL, len(refs)), "", true
+			}},
+		)
+	}
+
+	checks = append(checks, check{".hl files present", false, func() (string, string, bool) {
+		files, err := m.List()
+		if err != nil {
+			return err.Error(), "run 'schema-manager init' or check --cache-dir", false
+		}
+		if len(files) == 0 {
+			return "no .hl files found", "check that --repo points at a schema repository", false
+		}
+		return fmt.Sprintf("%d files", len(files)), "", true
+	}})
+
+	failed := 0
+	for _, c := range checks {
+		detail, hint, ok := c.run()
+		mark := paint(ansiGreen, "✓")
+		switch {
+		case !ok && c.critical:
+			mark = paint(ansiRed, "✗")
+			failed++
+		case !ok:
+			mark = paint(ansiYellow, "!")
+		}
+		fmt.Printf("%s %s: %s\n", mark, c.name, detail)
+		if !ok && hint != "" {
+			fmt.Printf("    hint: %s\n", hint)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	fmt.Println("All critical checks passed.")
+	return nil
+}
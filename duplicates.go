@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"schema-manager/schemamanager"
+
+	"github.com/spf13/cobra"
+)
+
+func newDuplicatesCmd() *cobra.Command {
+	var duplicatesCmd = &cobra.Command{
+		Use:   "duplicates",
+		Short: "Report .hl file names that appear in more than one directory",
+		Long: `Group the .hl files by file name and list every name found in more than one
+directory, with all of its paths. Such files confuse lookups by name. The exit
+code is 1 when any duplicates exist, so CI can enforce unique file names; with
+-o json the collisions are printed as an array of {name, paths} objects.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return findDuplicates()
+		},
+	}
+	duplicatesCmd.Flags().StringVar(&atRef, "ref", "", "Check the files in this branch, tag or commit in the cache instead of the working tree")
+	annotate(lockAnnotation, lockShared, duplicatesCmd)
+	return duplicatesCmd
+}
+
+func findDuplicates() error {
+	dups, err := newManager().Duplicates()
+	if err != nil {
+		return err
+	}
+
+	if outputFmt == "json" {
+		if dups == nil {
+			dups = []schemamanager.Duplicate{}
+		}
+		if err := writeStructured(dups); err != nil {
+			return err
+		}
+		if len(dups) > 0 {
+			return &exitError{code: exitFailure}
+		}
+		return nil
+	}
+
+	if len(dups) == 0 {
+		infof("%s No duplicate .hl file names.\n", paint(ansiGreen, "✓"))
+		return nil
+	}
+	for _, d := range dups {
+		fmt.Printf("%s %s (%d files)\n", paint(ansiRed, "✗"), d.Name, len(d.Paths))
+		for _, p := range d.Paths {
+			fmt.Printf("    %s\n", p)
+		}
+	}
+	return fmt.Errorf("%d .hl file name(s) appear in more than one directory", len(dups))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"schema-manager/schemamanager"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// 设置了这个环境变量时测试二进制直接运行 main，用来检查真实的退出码
+const runMainEnv = "SCHEMA_MANAGER_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		os.Args = append([]string{"schema-manager"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// 在独立的 HOME 中运行 schema-manager，返回启动的进程
+func startCLI(t *testing.T, home string, args ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	env := []string{runMainEnv + "=1", "HOME=" + home, "NO_COLOR=1"}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "OPENCMD_") && !strings.HasPrefix(kv, "HOME=") {
+			env = append(env, kv)
+		}
+	}
+	cmd.Env = env
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+// 运行 schema-manager 直到结束，返回退出码
+func runCLI(t *testing.T, home string, args ...string) int {
+	t.Helper()
+	return waitCLI(t, startCLI(t, home, args...))
+}
+
+func waitCLI(t *testing.T, cmd *exec.Cmd) int {
+	t.Helper()
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
+// 创建只有一个提交的源仓库，返回目录和用于追加提交的函数
+func sourceRepo(t *testing.T) (string, func()) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "src")
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	commit := func() {
+		t.Helper()
+		n++
+		name := fmt.Sprintf("cmd%d.hl", n)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("cmd c {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000+int64(n)*60, 0)}
+		if _, err := w.Commit(name, &git.CommitOptions{Author: sig}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit()
+	return dir, commit
+}
+
+func TestExitCodeMapping(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"generic", errors.New("boom"), exitFailure},
+		{"not initialized", fmt.Errorf("list: %w", schemamanager.ErrNotInitialized), exitNotInitialized},
+		{"corrupt cache", schemamanager.ErrCorruptCache, exitNotInitialized},
+		{"unreachable", fmt.Errorf("fetch: %w", schemamanager.ErrRemoteUnavailable), exitUnreachable},
+		{"rate limited", &schemamanager.RateLimitError{Host: "github.com"}, exitUnreachable},
+		{"locked", schemamanager.ErrLocked, exitLocked},
+		{"explicit code", &exitError{code: exitBehind}, exitBehind},
+		{"wrapped explicit code", fmt.Errorf("status: %w", &exitError{code: exitNoMatches}), exitNoMatches},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodeUsageError(t *testing.T) {
+	home := t.TempDir()
+	if got := runCLI(t, home, "list", "--no-such-flag"); got != exitFailure {
+		t.Errorf("unknown flag: exit %d, want %d", got, exitFailure)
+	}
+	if got := runCLI(t, home, "no-such-command"); got != exitFailure {
+		t.Errorf("unknown command: exit %d, want %d", got, exitFailure)
+	}
+}
+
+func TestExitCodeNotInitialized(t *testing.T) {
+	if got := runCLI(t, t.TempDir(), "list"); got != exitNotInitialized {
+		t.Errorf("list without a cache: exit %d, want %d", got, exitNotInitialized)
+	}
+}
+
+func TestExitCodeNoMatches(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "git.hl"), []byte("cmd git {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	home := t.TempDir()
+	if got := runCLI(t, home, "--local", dir, "search", "git"); got != 0 {
+		t.Errorf("search with a match: exit %d, want 0", got)
+	}
+	if got := runCLI(t, home, "--local", dir, "search", "no-such-text"); got != exitNoMatches {
+		t.Errorf("search without a match: exit %d, want %d", got, exitNoMatches)
+	}
+}
+
+func TestExitCodeBehind(t *testing.T) {
+	src, commit := sourceRepo(t)
+	home := t.TempDir()
+	if got := runCLI(t, home, "--repo", src, "init"); got != 0 {
+		t.Fatalf("init: exit %d", got)
+	}
+	if got := runCLI(t, home, "--repo", src, "status"); got != 0 {
+		t.Errorf("status of an up-to-date cache: exit %d, want 0", got)
+	}
+	// 第一次 status 缓存了远程引用，--refresh 重新查询
+	commit()
+	if got := runCLI(t, home, "--repo", src, "status", "--refresh"); got != exitBehind {
+		t.Errorf("status of a cache behind the remote: exit %d, want %d", got, exitBehind)
+	}
+}
+
+func TestExitCodeUnreachable(t *testing.T) {
+	// 关闭的服务器地址上没有进程监听，连接会被立即拒绝
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL + "/commands.git"
+	srv.Close()
+	if got := runCLI(t, t.TempDir(), "--repo", url, "--retries", "0", "init"); got != exitUnreachable {
+		t.Errorf("init from an unreachable remote: exit %d, want %d", got, exitUnreachable)
+	}
+}
+
+func TestExitCodeInterrupted(t *testing.T) {
+	// Windows 不能向其他进程发送 os.Interrupt
+	if runtime.GOOS == "windows" {
+		t.Skip("sending os.Interrupt is not supported on Windows")
+	}
+	// 服务器收到请求后一直不响应，直到测试结束
+	requested := make(chan struct{}, 1)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	cmd := startCLI(t, t.TempDir(), "--repo", srv.URL+"/commands.git", "--retries", "0", "init")
+	select {
+	case <-requested:
+	case <-time.After(10 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("init did not contact the remote")
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitCLI(t, cmd); got != exitInterrupted {
+		t.Errorf("interrupted init: exit %d, want %d", got, exitInterrupted)
+	}
+}
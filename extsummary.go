@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"schema-manager/schemamanager"
+)
+
+// search --show-ext 在每个结果前标出扩展名，--ext-summary 在最后按扩展名统计匹配数
+var showExt, extSummary bool
+
+// 带有扩展名的匹配，JSON 中和 Match 的字段平铺在一起
+type extMatch struct {
+	schemamanager.Match
+	Ext string `json:"ext"`
+}
+
+func checkExtFlags() error {
+	if !showExt && !extSummary {
+		return nil
+	}
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--count", countOnly},
+		{"--files-with-matches", withMatch},
+		{"--files-without-match", noMatch},
+		{"--null", nullSep},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("--show-ext and --ext-summary cannot be combined with %s", c.flag)
+		}
+	}
+	if extSummary && outputFmt == "json" {
+		return errors.New("--ext-summary is only available for text output; use --show-ext with -o json to get the extension of each match")
+	}
+	return nil
+}
+
+// 匹配行前的扩展名标注，--show-ext 时才有；同一次输出中按最长的扩展名对齐
+func extPrefix(m *schemamanager.Manager, path string, width int) string {
+	if !showExt {
+		return ""
+	}
+	ext := m.SchemaExt(path)
+	return paint(ansiYellow, fmt.Sprintf("%-*s", width+2, "["+ext+"]")) + " "
+}
+
+// 标注的宽度：结果中最长的扩展名
+func extWidth(m *schemamanager.Manager, matches []schemamanager.Match) int {
+	width := 0
+	for _, match := range matches {
+		width = max(width, len(m.SchemaExt(match.Path)))
+	}
+	return width
+}
+
+func withExt(m *schemamanager.Manager, matches []schemamanager.Match) []extMatch {
+	out := make([]extMatch, len(matches))
+	for i, match := range matches {
+		out[i] = extMatch{Match: match, Ext: m.SchemaExt(match.Path)}
+	}
+	return out
+}
+
+// 按扩展名统计匹配数和文件数，扩展名按匹配数从多到少排列
+func printExtSummary(m *schemamanager.Manager, matches []schemamanager.Match) {
+	hits := map[string]int{}
+	files := map[string]map[string]bool{}
+	for _, match := range matches {
+		ext := m.SchemaExt(match.Path)
+		hits[ext]++
+		if files[ext] == nil {
+			files[ext] = map[string]bool{}
+		}
+		files[ext][match.Path] = true
+	}
+	exts := make([]string, 0, len(hits))
+	width := 0
+	for ext := range hits {
+		exts = append(exts, ext)
+		width = max(width, len(ext))
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if hits[exts[i]] != hits[exts[j]] {
+			return hits[exts[i]] > hits[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+
+	fmt.Println("Matches by extension:")
+	for _, ext := range exts {
+		fmt.Printf("  %-*s  %d match(es) in %d file(s)\n", width, ext, hits[ext], len(files[ext]))
+	}
+}
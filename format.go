@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"schema-manager/schemamanager"
+)
+
+// --format 可用的预设模板
+var formatPresets = map[string]string{
+	"paths": "{{.Path}}",
+	"table": "{{.Path}}\t{{.Size}}\t{{.ModTime.Format \"2006-01-02 15:04\"}}",
+}
+
+// 模板中每个文件可用的字段
+type fileInfo struct {
+	Path    string
+	Name    string
+	Dir     string
+	Size    int64
+	ModTime time.Time
+}
+
+// 解析 --format，预设名称替换为对应模板
+func parseFormat(format string) (*template.Template, error) {
+	text := format
+	if preset, ok := formatPresets[format]; ok {
+		text = preset
+	}
+	tmpl, err := template.New("format").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// 对每个文件执行模板，每个结果占一行；table 预设按列对齐
+func printFormatted(tmpl *template.Template, files []schemamanager.File) error {
+	var w io.Writer = os.Stdout
+	var tw *tabwriter.Writer
+	if listFormat == "table" {
+		tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PATH\tSIZE\tMODIFIED")
+		w = tw
+	}
+
+	for _, f := range files {
+		path := filepath.ToSlash(f.Path)
+		dir := filepath.ToSlash(filepath.Dir(f.Path))
+		info := fileInfo{Path: path, Name: filepath.Base(f.Path), Dir: dir, Size: f.Size, ModTime: f.ModTime}
+
+		var b strings.Builder
+		if err := tmpl.Execute(&b, info); err != nil {
+			return fmt.Errorf("executing --format template: %w", err)
+		}
+		fmt.Fprintln(w, b.String())
+	}
+
+	if tw != nil {
+		return tw.Flush()
+	}
+	return nil
+}
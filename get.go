@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"schema-manager/schemamanager"
+
+	"github.com/spf13/cobra"
+)
+
+func newGetCmd() *cobra.Command {
+	var getCmd = &cobra.Command{
+		Use:   "get <command>",
+		Short: "Print or export the .hl file that declares a command",
+		Long: `Find the .hl file for a command by name instead of by path. The name is
+matched against the commands declared at the top level of each file
+('cmd git { ... }'); files without a declaration are known by their file name
+without the extension. The file is printed as with 'show', or copied into
+--dest as with 'export'. When several files declare the command, the
+candidates are listed and nothing is printed.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCommandNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return getCommand(args[0])
+		},
+	}
+	getCmd.Flags().BoolVar(&rawShow, "raw", false, "Print the file bytes unmodified, without line numbers")
+	getCmd.Flags().StringVar(&exportDest, "dest", "", "Copy the file into this directory instead of printing it")
+	getCmd.Flags().BoolVar(&flatten, "flatten", false, "With --dest, put the file directly in the directory instead of preserving its path")
+	getCmd.Flags().BoolVarP(&overwrite, "force", "f", false, "With --dest, overwrite an existing file")
+	getCmd.Flags().StringVar(&atRef, "ref", "", "Look the command up in this branch, tag or commit in the cache instead of the working tree")
+	annotate(lockAnnotation, lockShared, getCmd)
+	return getCmd
+}
+
+// 按命令名找到声明它的文件，唯一时输出或导出；JSON 模式下只输出候选路径
+func getCommand(name string) error {
+	m := newManager()
+	paths, err := m.FindCommand(name)
+	if err != nil {
+		return err
+	}
+
+	if outputFmt == "json" {
+		if paths == nil {
+			paths = []string{}
+		}
+		if err := writeStructured(paths); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return &exitError{code: exitNoMatches}
+		}
+		return nil
+	}
+
+	switch len(paths) {
+	case 0:
+		return &exitError{code: exitNoMatches, err: fmt.Errorf("no .hl file declares command %q", name)}
+	case 1:
+	default:
+		return fmt.Errorf("command %q is declared in %d files:\n  %s\nuse 'schema-manager show <path>' to pick one", name, len(paths), strings.Join(paths, "\n  "))
+	}
+
+	if exportDest == "" {
+		return showFile(paths[0])
+	}
+	// export 复制的是工作区中的文件
+	if atRef != "" {
+		return fmt.Errorf("--dest cannot be combined with --ref; use --raw and redirect the output instead")
+	}
+	if _, err := m.Export(paths, schemamanager.ExportOptions{Dest: exportDest, Flatten: flatten, Force: overwrite}); err != nil {
+		return err
+	}
+	infof("Exported %s to %s\n", paths[0], exportDest)
+	return nil
+}
+
+// 补全缓存中声明的命令名
+func completeCommandNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// 补全时不会执行 PersistentPreRunE，需要自己解析缓存目录
+	if err := resolveSettings(cmd); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	index, err := newManager().CommandIndex()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name := range index {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -2,7 +2,14 @@ module schema-manager
 
 go 1.24.2
 
-require github.com/go-git/go-git/v6 v6.0.0-20250819122726-39261590f7f3
+require (
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/go-git/go-git/v6 v6.0.0-20250819122726-39261590f7f3
+	github.com/spf13/cobra v1.9.1
+	golang.org/x/sys v0.35.0
+	golang.org/x/term v0.34.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	dario.cat/mergo v1.0.1 // indirect
@@ -18,10 +25,8 @@ require (
 	github.com/kevinburke/ssh_config v1.2.0 // indirect
 	github.com/pjbgf/sha1cd v0.4.0 // indirect
 	github.com/sergi/go-diff v1.4.0 // indirect
-	github.com/spf13/cobra v1.9.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 	golang.org/x/crypto v0.41.0 // indirect
 	golang.org/x/exp v0.0.0-20250531010427-b6e5de432a8b // indirect
 	golang.org/x/net v0.43.0 // indirect
-	golang.org/x/sys v0.35.0 // indirect
 )
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"schema-manager/schemamanager"
+)
+
+// list --group-by 的分组方式：dir、ext 或 none
+var groupBy string
+
+// 检查分组方式，以及不能和分组一起使用的输出选项
+func checkGroupBy() error {
+	switch groupBy {
+	case "", "none":
+		return nil
+	case "dir", "ext":
+	default:
+		return fmt.Errorf("invalid --group-by %q: must be dir, ext or none", groupBy)
+	}
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--flat", flatList},
+		{"--dirs-only", dirsOnly},
+		{"--files-only", filesOnly},
+		{"--format", listFormat != ""},
+		{"--since", since != ""},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("--group-by %s cannot be combined with %s", groupBy, c.flag)
+		}
+	}
+	return nil
+}
+
+func grouped() bool {
+	return groupBy == "dir" || groupBy == "ext"
+}
+
+// 文件所属的分组：dir 取第一级目录，缓存根目录下的文件归到 .；ext 取扩展名
+func groupKey(path string) string {
+	path = filepath.ToSlash(path)
+	if groupBy == "ext" {
+		if ext := filepath.Ext(path); ext != "" {
+			return ext
+		}
+		return "(none)"
+	}
+	if i := strings.Index(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return "."
+}
+
+// 把文件按分组归类，组内保持 files 原来的顺序，返回排好序的组名
+func groupFiles(files []schemamanager.File) ([]string, map[string][]schemamanager.File) {
+	groups := map[string][]schemamanager.File{}
+	var keys []string
+	for _, f := range files {
+		key := groupKey(f.Path)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], f)
+	}
+	sort.Strings(keys)
+	if reverse {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+	return keys, groups
+}
+
+// 分组输出：每组一个带文件数的标题，下面是组内文件的相对路径；返回组数
+func printGroups(files []schemamanager.File) int {
+	keys, groups := groupFiles(files)
+	for i, key := range keys {
+		if i > 0 {
+			fmt.Println()
+		}
+		header := key
+		if groupBy == "dir" && key != "." {
+			header += "/"
+		}
+		fmt.Printf("%s (%d)\n", paint(ansiBlue, header), len(groups[key]))
+		for _, f := range groups[key] {
+			dir, name := filepath.Split(filepath.ToSlash(f.Path))
+			fmt.Printf("  %s%s\n", paint(ansiBlue, dir), paint(ansiCyan, name))
+		}
+	}
+	return len(keys)
+}
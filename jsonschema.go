@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"schema-manager/schemamanager"
+)
+
+// export --as：hl 原样复制，jsonschema 转换为 JSON Schema
+var exportAs = "hl"
+
+func checkExportAs() error {
+	switch exportAs {
+	case "hl":
+		if exportDest == "" {
+			return fmt.Errorf(`required flag(s) "dest" not set`)
+		}
+	case "jsonschema":
+	default:
+		return fmt.Errorf("invalid --as %q: must be hl or jsonschema", exportAs)
+	}
+	return nil
+}
+
+// export --as jsonschema：有 --dest 时每个文件写成 <name>.schema.json，否则输出到 stdout，
+// 多个文件时输出以路径为键的对象。无法转换的文件在 stderr 报告并跳过，有跳过的文件时退出码为 1
+func exportJSONSchema(paths []string) error {
+	m := newManager()
+	if exportDest != "" {
+		n, skipped, err := m.ExportJSONSchema(paths, schemamanager.ExportOptions{Dest: exportDest, Flatten: flatten, Force: overwrite})
+		reportSkipped(skipped)
+		if err != nil {
+			return err
+		}
+		infof("Exported %d file(s) as JSON Schema to %s\n", n, exportDest)
+		if len(skipped) > 0 {
+			infof("Skipped %d file(s) that could not be translated\n", len(skipped))
+			return &exitError{code: exitFailure}
+		}
+		return nil
+	}
+
+	schemas, skipped, err := m.JSONSchemas(paths)
+	if err != nil {
+		return err
+	}
+	reportSkipped(skipped)
+	docs := make(map[string]json.RawMessage, len(paths))
+	for i, p := range paths {
+		if schemas[i] != nil {
+			docs[p] = schemas[i]
+		}
+	}
+
+	var out []byte
+	switch {
+	case len(docs) == 0:
+	case len(paths) == 1:
+		out = docs[paths[0]]
+	default:
+		if out, err = json.MarshalIndent(docs, "", "  "); err != nil {
+			return err
+		}
+	}
+	if out != nil {
+		fmt.Println(string(out))
+	}
+	if len(skipped) > 0 {
+		return &exitError{code: exitFailure}
+	}
+	return nil
+}
+
+func reportSkipped(skipped []schemamanager.ExportSkip) {
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "skipping %s: %v\n", s.Path, s.Err)
+	}
+}
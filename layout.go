@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"schema-manager/schemamanager"
+
+	"github.com/spf13/cobra"
+)
+
+// ~/.opencmd 目录结构的版本，记录在 ~/.opencmd/layout 中。
+// 版本 0：没有 layout 文件，默认仓库克隆在 commands，它的索引等状态文件直接放在 ~/.opencmd 下；
+// 版本 1：每个仓库配置（包括 default）都在 profiles/<name> 中，克隆在 commands，状态文件和它放在同一级
+const currentLayout = 1
+
+// 没有 layout 文件的目录来自记录版本之前
+const unversionedLayout = 0
+
+// 把 ~/.opencmd 从版本 from 升级到 from+1 的迁移
+type layoutMigration struct {
+	from    int
+	summary string
+	// moves 中的路径相对 ~/.opencmd，源不存在时跳过
+	moves []layoutMove
+}
+
+type layoutMove struct {
+	from, to string
+}
+
+// 按 from 升序排列。改变目录结构时增加 currentLayout，并在这里加入对应的迁移
+var layoutMigrations = []layoutMigration{
+	{
+		from:    0,
+		summary: "the default repository moves to profiles/default like the other profiles",
+		moves: []layoutMove{
+			{"commands", "profiles/default/commands"},
+			{"index.json", "profiles/default/index.json"},
+			{"remote.json", "profiles/default/remote.json"},
+			{"commits.json", "profiles/default/commits.json"},
+		},
+	},
+}
+
+// 缓存位于 ~/.opencmd 管理的默认位置时为 ~/.opencmd，由 resolveSettings 设置；
+// 缓存目录由用户指定或使用 --local 时为空，这时不检查也不迁移目录结构
+var layoutBase string
+
+// 不读写缓存的命令不检查目录结构，也不会在 ~/.opencmd 中写入任何内容
+func skipsLayout(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case "help", "version", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return false
+}
+
+// 启动时检查 base 的结构版本，旧版本逐级迁移到当前版本：移动前把源复制到 backup 目录中，
+// 迁移的内容输出到 report。base 不存在时（还没有 init）不需要检查
+func migrateLayout(base string, report io.Writer) error {
+	if info, err := os.Stat(base); err != nil || !info.IsDir() {
+		return nil
+	}
+	version, recorded, err := readLayout(base)
+	if err != nil {
+		return err
+	}
+	if version > currentLayout {
+		return fmt.Errorf("%s uses cache layout %d, but this schema-manager only supports up to %d; upgrade schema-manager", base, version, currentLayout)
+	}
+	if recorded && version == currentLayout {
+		return nil
+	}
+
+	// 迁移期间持有旧位置的锁，避免移动正在被其他进程使用的缓存
+	if version < currentLayout {
+		release, err := (&schemamanager.Manager{LockPath: filepath.Join(base, "lock")}).Lock(true, lockTimeout)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	for _, mig := range layoutMigrations {
+		if mig.from < version {
+			continue
+		}
+		if err := runMigration(base, mig, report); err != nil {
+			return fmt.Errorf("migrating %s from layout %d to %d: %w", base, mig.from, mig.from+1, err)
+		}
+		version = mig.from + 1
+		if err := writeLayout(base, version); err != nil {
+			return err
+		}
+	}
+
+	// 没有可执行的迁移时只补上版本记录；目录只读时不影响其他操作，下次运行时再记录
+	if !recorded && version == currentLayout {
+		if err := writeLayout(base, version); err != nil {
+			debugf(1, "recording cache layout: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// 返回记录的版本，没有记录时返回 unversionedLayout 和 false
+func readLayout(base string) (int, bool, error) {
+	data, err := os.ReadFile(filepath.Join(base, "layout"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return unversionedLayout, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading cache layout version: %w", err)
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || version < 0 {
+		return 0, false, fmt.Errorf("invalid cache layout version %q in %s", strings.TrimSpace(string(data)), filepath.Join(base, "layout"))
+	}
+	return version, true, nil
+}
+
+func writeLayout(base string, version int) error {
+	return os.WriteFile(filepath.Join(base, "layout"), []byte(strconv.Itoa(version)+"\n"), 0644)
+}
+
+// 依次备份并移动每个路径；目标已经存在时停止，不覆盖任何内容
+func runMigration(base string, mig layoutMigration, report io.Writer) error {
+	backup := filepath.Join(base, "backup", fmt.Sprintf("layout-%d-%s", mig.from, time.Now().Format("20060102-150405")))
+	moved := 0
+	for _, mv := range mig.moves {
+		src, dst := filepath.Join(base, filepath.FromSlash(mv.from)), filepath.Join(base, filepath.FromSlash(mv.to))
+		if _, err := os.Lstat(src); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if moved == 0 {
+			fmt.Fprintf(report, "Migrating %s to cache layout %d: %s\n", base, mig.from+1, mig.summary)
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			return fmt.Errorf("cannot move %s: %s already exists", mv.from, mv.to)
+		}
+		if err := copyTree(src, filepath.Join(backup, filepath.FromSlash(mv.from))); err != nil {
+			return fmt.Errorf("backing up %s: %w", mv.from, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+		fmt.Fprintf(report, "  moved %s to %s\n", mv.from, mv.to)
+		moved++
+	}
+	if moved > 0 {
+		fmt.Fprintf(report, "  backup of the moved files: %s\n", backup)
+	}
+	return nil
+}
+
+// 递归复制文件或目录，保留权限和符号链接
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// 在临时 HOME 中创建版本 0 的 ~/.opencmd：默认仓库克隆在 commands，状态文件在同一级。返回 HOME 和 ~/.opencmd
+func legacyHome(t *testing.T) (string, string) {
+	t.Helper()
+	src, _ := sourceRepo(t)
+	home := t.TempDir()
+	base := filepath.Join(home, ".opencmd")
+	if _, err := git.PlainClone(filepath.Join(base, "commands"), &git.CloneOptions{URL: src}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"index.json": "{}\n", "remote.json": "{}\n", "config.yaml": "repo: " + src + "\n"} {
+		if err := os.WriteFile(filepath.Join(base, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home, base
+}
+
+func readLayoutFile(t *testing.T, base string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(base, "layout"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMigrateLayoutMovesDefaultClone(t *testing.T) {
+	_, base := legacyHome(t)
+	var report bytes.Buffer
+	if err := migrateLayout(base, &report); err != nil {
+		t.Fatalf("migrateLayout: %v", err)
+	}
+
+	// 克隆整体移动到 profiles/default/commands，仍然是可用的仓库
+	moved := filepath.Join(base, "profiles", "default", "commands")
+	repo, err := git.PlainOpen(moved)
+	if err != nil {
+		t.Fatalf("opening the moved clone: %v", err)
+	}
+	if _, err := repo.Head(); err != nil {
+		t.Errorf("moved clone has no HEAD: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(moved, "cmd1.hl")); err != nil {
+		t.Errorf("moved clone lost its files: %v", err)
+	}
+	for _, name := range []string{"commands", "index.json", "remote.json"} {
+		if _, err := os.Lstat(filepath.Join(base, name)); !os.IsNotExist(err) {
+			t.Errorf("%s is still in the old location", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(base, "profiles", "default", "index.json")); err != nil {
+		t.Errorf("index.json was not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "config.yaml")); err != nil {
+		t.Errorf("config.yaml was moved: %v", err)
+	}
+
+	// 移动前的内容完整备份
+	backups, err := filepath.Glob(filepath.Join(base, "backup", "layout-0-*"))
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("backups = %v, %v; want one layout-0 backup", backups, err)
+	}
+	if _, err := git.PlainOpen(filepath.Join(backups[0], "commands")); err != nil {
+		t.Errorf("backup of the clone is not a repository: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(backups[0], "remote.json")); err != nil {
+		t.Errorf("remote.json was not backed up: %v", err)
+	}
+
+	for _, want := range []string{
+		"Migrating " + base + " to cache layout 1",
+		"moved commands to profiles/default/commands",
+		"moved index.json to profiles/default/index.json",
+		"backup of the moved files: " + backups[0],
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("report does not mention %q:\n%s", want, report.String())
+		}
+	}
+	if got, want := readLayoutFile(t, base), strconv.Itoa(currentLayout)+"\n"; got != want {
+		t.Errorf("layout = %q, want %q", got, want)
+	}
+
+	// 再次运行时没有需要迁移的内容
+	report.Reset()
+	if err := migrateLayout(base, &report); err != nil || report.Len() > 0 {
+		t.Errorf("second migrateLayout = %v, report %q; want no migration", err, report.String())
+	}
+}
+
+// 目标已经存在时不覆盖，也不记录新版本
+func TestMigrateLayoutKeepsExistingTarget(t *testing.T) {
+	_, base := legacyHome(t)
+	target := filepath.Join(base, "profiles", "default", "commands")
+	if err := os.MkdirAll(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateLayout(base, &bytes.Buffer{}); err == nil {
+		t.Fatal("migrateLayout overwrote an existing profiles/default/commands")
+	}
+	if _, err := os.Stat(filepath.Join(base, "commands", ".git")); err != nil {
+		t.Errorf("the old clone was moved: %v", err)
+	}
+	if got := readLayoutFile(t, base); got != "" {
+		t.Errorf("layout = %q after a failed migration, want no record", got)
+	}
+}
+
+// 已经记录了当前版本时不再写入
+func TestMigrateLayoutKeepsCurrentVersion(t *testing.T) {
+	base := t.TempDir()
+	path := filepath.Join(base, "layout")
+	if err := writeLayout(base, currentLayout); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateLayout(base, &bytes.Buffer{}); err != nil {
+		t.Fatalf("migrateLayout: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("layout file was rewritten: mtime %v, want %v", info.ModTime(), old)
+	}
+}
+
+func TestMigrateLayoutRejectsNewerVersion(t *testing.T) {
+	base := t.TempDir()
+	if err := writeLayout(base, currentLayout+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateLayout(base, &bytes.Buffer{}); err == nil {
+		t.Error("migrateLayout accepted a layout newer than this version")
+	}
+}
+
+func TestMigrateLayoutRejectsInvalidVersion(t *testing.T) {
+	base := t.TempDir()
+	if err := os.WriteFile(filepath.Join(base, "layout"), []byte("v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateLayout(base, &bytes.Buffer{}); err == nil {
+		t.Error("migrateLayout accepted an invalid layout version")
+	}
+}
+
+// 普通命令在使用默认缓存时迁移，之后读取的是移动后的克隆
+func TestMigrateLayoutOnCommand(t *testing.T) {
+	home, base := legacyHome(t)
+	if got := runCLI(t, home, "list"); got != 0 {
+		t.Fatalf("list: exit %d", got)
+	}
+	if _, err := os.Stat(filepath.Join(base, "profiles", "default", "commands", ".git")); err != nil {
+		t.Errorf("list did not migrate the default clone: %v", err)
+	}
+}
+
+// 不读写缓存的命令和使用自己的缓存目录时不修改 ~/.opencmd
+func TestMigrateLayoutSkipped(t *testing.T) {
+	home, base := legacyHome(t)
+	for _, args := range [][]string{
+		{"version"},
+		{"help"},
+		{"completion", "bash"},
+		{"--cache-dir", filepath.Join(t.TempDir(), "commands"), "list"},
+	} {
+		runCLI(t, home, args...)
+		if _, err := os.Stat(filepath.Join(base, "commands", ".git")); err != nil {
+			t.Errorf("%v moved the default clone: %v", args, err)
+		}
+		if got := readLayoutFile(t, base); got != "" {
+			t.Errorf("%v wrote layout %q", args, got)
+		}
+	}
+}
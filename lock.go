@@ -0,0 +1,38 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// 命令注解中记录需要的缓存锁
+const (
+	lockAnnotation = "lock"
+	lockExclusive  = "exclusive"
+	lockShared     = "shared"
+)
+
+// 等待缓存锁的最长时间
+var lockTimeout time.Duration
+
+// 当前进程持有的缓存锁，main 退出前释放
+var releaseLock = func() error { return nil }
+
+// 按命令的注解获取缓存锁；--local 目录不归本工具管理，--watch 会一直运行，都不加锁。
+// search --write 会修改文件，需要独占锁
+func acquireLock(cmd *cobra.Command) error {
+	mode := cmd.Annotations[lockAnnotation]
+	if writeBack && mode != "" {
+		mode = lockExclusive
+	}
+	if mode == "" || localDir != "" || (mode == lockShared && watchMode) {
+		return nil
+	}
+	release, err := newManager().Lock(mode == lockExclusive, lockTimeout)
+	if err != nil {
+		return err
+	}
+	releaseLock = release
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/go-git/go-git/v6/utils/trace"
+)
+
+// --quiet 时只输出错误
+var quiet bool
+
+// 日常的进度提示，--quiet 时不输出
+func infof(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+func infoln(args ...any) {
+	if !quiet {
+		fmt.Println(args...)
+	}
+}
+
+// 调试信息写到 stderr，-v 的次数达到 level 时才输出
+func debugf(level int, format string, args ...any) {
+	if verbose >= level {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// 检查 --verbose 和 --quiet，-vv 打开 go-git 的传输跟踪，-vvv 再加上协议包
+func resolveLogging() error {
+	if quiet && verbose > 0 {
+		return fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+	if verbose >= 2 {
+		target := trace.General | trace.HTTP | trace.SSH
+		if verbose >= 3 {
+			target |= trace.Packet
+		}
+		trace.SetLogger(log.New(os.Stderr, "git: ", log.Ltime|log.Lmicroseconds))
+		trace.SetTarget(target)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"schema-manager/schemamanager"
+	"schema-manager/schemamanager/hl"
+)
+
+// list --show-meta：列出文件头部声明的版本、目标和说明
+var showMeta bool
+
+// 带有头部元数据的文件，JSON 中和 File 的字段平铺在一起
+type metaFile struct {
+	schemamanager.File
+	Meta *hl.Meta `json:"meta,omitempty"`
+}
+
+// 按当前排序列出文件及其元数据，没有声明的列显示为 -
+func listWithMeta(files []schemamanager.File) error {
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--since", since != ""},
+		{"--dirs-only", dirsOnly},
+		{"--files-only", filesOnly},
+		{"--format", listFormat != ""},
+		{"--group-by", grouped()},
+		{"--by-commit-date", byCommit},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("--show-meta cannot be combined with %s", c.flag)
+		}
+	}
+
+	metas, err := newManager().Metadata(files)
+	if err != nil {
+		return err
+	}
+	list := make([]metaFile, len(files))
+	declared := 0
+	for i, f := range files {
+		list[i].File = f
+		if !metas[i].IsZero() {
+			list[i].Meta = &metas[i]
+			declared++
+		}
+	}
+
+	if structured() {
+		return writeStructured(list)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tVERSION\tTARGET\tDESCRIPTION")
+	for _, f := range list {
+		var m hl.Meta
+		if f.Meta != nil {
+			m = *f.Meta
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Path, orDash(m.Version), orDash(m.Target), orDash(m.Description))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Found %d .hl files, %d with metadata\n", len(files), declared)
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+// --null：路径之间用 NUL 分隔，和 find -print0 一样每个路径后面都有一个 NUL，可以交给 xargs -0
+var nullSep bool
+
+// --null 只输出路径，不能和改变输出内容的选项同时使用
+func checkNull(command string) error {
+	if !nullSep {
+		return nil
+	}
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--output " + outputFmt, structured() || outputFmt == "jsonl"},
+		{"--count", countOnly},
+		{"--watch", watchMode},
+		{"--format", command == "list" && listFormat != ""},
+		{"--group-by", command == "list" && grouped()},
+		{"--by-commit-date", command == "list" && byCommit},
+		{"--show-meta", command == "list" && showMeta},
+		// 内容搜索输出的是匹配行，只有 -l 和 -L 输出路径
+		{"--content without -l or -L", command == "search" && searchBody && !withMatch && !noMatch},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("--null cannot be combined with %s", c.flag)
+		}
+	}
+	return nil
+}
+
+// 输出一个路径，--null 时以 NUL 结尾，否则以换行结尾
+func printEntry(p string) {
+	if nullSep {
+		fmt.Print(p + "\x00")
+		return
+	}
+	fmt.Println(p)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// 在浏览器中打开文件在托管网站上的页面，--editor 时用 $VISUAL 或 $EDITOR 打开本地文件
+func openFile(relPath string) error {
+	m := newManager()
+	if useEditor {
+		if m.IsBare() || m.IsNoCheckout() {
+			return errors.New("--editor is not available for a bare or no-checkout cache")
+		}
+		path, err := m.Resolve(relPath)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+		return runEditor(path)
+	}
+
+	if err := requireGit("open"); err != nil {
+		return err
+	}
+	webURL, err := m.WebURL(relPath)
+	if err != nil {
+		return err
+	}
+	if printURL {
+		fmt.Println(webURL)
+		return nil
+	}
+	return openBrowser(webURL)
+}
+
+// 编辑器命令可以带参数，例如 "code --wait"
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return errors.New("no editor configured; set $VISUAL or $EDITOR")
+	}
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running editor %s: %w", fields[0], err)
+	}
+	return nil
+}
+
+// 优先使用 $BROWSER，否则使用各平台打开链接的默认方式
+func openBrowser(webURL string) error {
+	var name string
+	var args []string
+	if browser := strings.Fields(os.Getenv("BROWSER")); len(browser) > 0 {
+		name, args = browser[0], browser[1:]
+	} else {
+		switch runtime.GOOS {
+		case "darwin":
+			name = "open"
+		case "windows":
+			name, args = "rundll32", []string{"url.dll,FileProtocolHandler"}
+		default:
+			name = "xdg-open"
+		}
+	}
+	cmd := exec.Command(name, append(args, webURL)...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("opening %s with %s: %w (use --print to show the URL instead)", webURL, name, err)
+	}
+	return nil
+}
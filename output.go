@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// 支持 --output yaml 和 --output jsonl 的命令分别带有这两个注解
+const (
+	yamlAnnotation  = "yaml"
+	jsonlAnnotation = "jsonl"
+)
+
+// 给命令设置注解，例如需要的缓存锁
+func annotate(key, value string, cmds ...*cobra.Command) {
+	for _, c := range cmds {
+		if c.Annotations == nil {
+			c.Annotations = map[string]string{}
+		}
+		c.Annotations[key] = value
+	}
+}
+
+// --output 的全部取值，config set output 按同一列表检查
+var outputFormats = []string{"text", "json", "yaml", "jsonl"}
+
+// 检查 format 是否是 outputFormats 之一
+func validOutputFormat(format string) error {
+	if slices.Contains(outputFormats, format) {
+		return nil
+	}
+	last := len(outputFormats) - 1
+	return fmt.Errorf("invalid output format %q: must be %s or %s", format, strings.Join(outputFormats[:last], ", "), outputFormats[last])
+}
+
+// 检查 --output 的取值，yaml 和 jsonl 只用于带有对应注解的命令；取值来自配置文件时，
+// 不支持它的命令退回 json，而不是让所有命令都报错
+func checkOutputFormat(cmd *cobra.Command) error {
+	if err := validOutputFormat(outputFmt); err != nil {
+		return err
+	}
+	var msg string
+	switch {
+	case outputFmt == "yaml" && cmd.Annotations[yamlAnnotation] == "":
+		msg = "--output yaml is only supported by list, status and stats"
+	case outputFmt == "jsonl" && cmd.Annotations[jsonlAnnotation] == "":
+		msg = "--output jsonl is only supported by list"
+	default:
+		return nil
+	}
+	if f := cmd.Flag("output"); f != nil && !f.Changed {
+		debugf(1, "configured output %s is not supported by %s; using json\n", outputFmt, cmd.Name())
+		outputFmt = "json"
+		return nil
+	}
+	return errors.New(msg)
+}
+
+// 报告是否输出 JSON、YAML 或 JSON Lines；这些模式下 stdout 只有结果，提示信息写到 stderr
+func structured() bool {
+	return outputFmt == "json" || outputFmt == "yaml" || outputFmt == "jsonl"
+}
+
+// 按 --output 把 v 编码到 stdout。YAML 由 JSON 转换而来，字段名和 JSON 一致
+func writeStructured(v any) error {
+	if outputFmt != "yaml" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// JSON 也是合法的 YAML，解析成节点可以保留字段顺序
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// 去掉从 JSON 继承的流式和引号风格，字符串只在必要时加引号
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// 没有设置 $PAGER 时使用的分页程序
+const defaultPager = "less"
+
+// 解析 --paginate，返回本次输出是否可能分页；非终端和 JSON 输出从不分页
+func resolvePaging() (bool, error) {
+	switch paginate {
+	case "auto", "always", "never":
+	default:
+		return false, fmt.Errorf("invalid paginate mode %q: must be auto, always or never", paginate)
+	}
+	return paginate != "never" && !structured() && isTerminal(os.Stdout), nil
+}
+
+// 执行 run 并收集它写到 stdout 的内容；auto 模式下超过终端高度、always 模式下总是交给分页程序显示
+func withPager(run func() error) error {
+	page, err := resolvePaging()
+	if err != nil {
+		return err
+	}
+	if !page {
+		return run()
+	}
+
+	// 临时把 stdout 换成管道，run 中的输出都先进入缓冲区
+	r, w, err := os.Pipe()
+	if err != nil {
+		return run()
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	var buf bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(copied)
+	}()
+
+	runErr := run()
+	w.Close()
+	os.Stdout = stdout
+	<-copied
+	r.Close()
+
+	if paginate == "auto" && !exceedsScreen(buf.Bytes()) {
+		_, err := stdout.Write(buf.Bytes())
+		return firstErr(runErr, err)
+	}
+	return firstErr(runErr, runPager(buf.Bytes()))
+}
+
+// 报告内容的行数是否超过终端高度
+func exceedsScreen(out []byte) bool {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height <= 0 {
+		return false
+	}
+	return bytes.Count(out, []byte("\n")) >= height
+}
+
+// 把内容交给 $PAGER（默认 less）显示；分页程序无法启动时直接输出
+func runPager(out []byte) error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 || args[0] == "cat" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(out)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// 和 git 一样：保留颜色，内容不足一屏时直接退出，退出后不清屏
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil
+		}
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	return nil
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 列出候选文件并让用户按编号选择；multi 为 true 时可以选择多个，例如 "1 3 5-7" 或 "all"。
+// 提示写到 stderr，stdout 只输出命令本身的结果
+func pickFiles(candidates []string, multi bool) ([]string, error) {
+	if !isTerminal(os.Stdin) {
+		return nil, errors.New("--interactive requires a terminal; pass the path explicitly instead")
+	}
+	if len(candidates) == 0 {
+		return nil, &exitError{code: exitNoMatches, err: errors.New("no .hl files to choose from")}
+	}
+
+	width := len(strconv.Itoa(len(candidates)))
+	for i, c := range candidates {
+		fmt.Fprintf(os.Stderr, "  %*d) %s\n", width, i+1, paint(ansiCyan, c))
+	}
+
+	prompt := "Select a file [1-%d]: "
+	if multi {
+		prompt = "Select files (e.g. 1 3 5-7, or all) [1-%d]: "
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, prompt, len(candidates))
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if err != nil {
+				return nil, errors.New("no file selected")
+			}
+			continue
+		}
+
+		picked, perr := parseSelection(line, len(candidates), multi)
+		if perr != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", perr)
+			if err != nil {
+				return nil, perr
+			}
+			continue
+		}
+		selected := make([]string, len(picked))
+		for i, n := range picked {
+			selected[i] = candidates[n-1]
+		}
+		return selected, nil
+	}
+}
+
+// 解析逗号或空格分隔的编号和范围，返回去重后按输入顺序排列的编号
+func parseSelection(input string, n int, multi bool) ([]int, error) {
+	if multi && strings.EqualFold(input, "all") {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i + 1
+		}
+		return all, nil
+	}
+
+	var picked []int
+	seen := map[int]bool{}
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil || first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("invalid selection %q: enter numbers between 1 and %d", field, n)
+		}
+		for i := first; i <= last; i++ {
+			if !seen[i] {
+				seen[i] = true
+				picked = append(picked, i)
+			}
+		}
+	}
+	if !multi && len(picked) != 1 {
+		return nil, fmt.Errorf("select exactly one file")
+	}
+	return picked, nil
+}
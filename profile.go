@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"schema-manager/schemamanager"
+
+	"github.com/spf13/cobra"
+)
+
+// 默认仓库配置名，对应配置文件顶层的 repo、branch 和 cache-dir
+const defaultProfile = "default"
+
+// 当前使用的仓库配置，由 --profile、OPENCMD_PROFILE 或 repo use 决定
+var activeProfile = defaultProfile
+
+func newRepoCmd() *cobra.Command {
+	var repoCmd = &cobra.Command{
+		Use:   "repo",
+		Short: "Manage named schema repositories",
+		Long: `Manage named schema repositories (profiles). Each profile has its own URL,
+branch and cache directory (~/.opencmd/profiles/<name>/commands by default).
+init, list, search, status and the other commands operate on the active
+profile, selected with 'repo use', --profile or OPENCMD_PROFILE.
+
+The "default" profile uses the top-level repo, branch and cache-dir from the
+config file and points at opencommand/commands unless configured otherwise.`,
+	}
+
+	var addBranch, addCacheDir string
+	var addCmd = &cobra.Command{
+		Use:   "add <name> <url>",
+		Short: "Add or replace a named repository",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, url := args[0], args[1]
+			if name == defaultProfile {
+				return fmt.Errorf("profile %q is reserved; use 'schema-manager config set repo' instead", name)
+			}
+			if name == "" || name != filepath.Base(name) || name[0] == '.' {
+				return fmt.Errorf("invalid profile name %q", name)
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if cfg.Profiles == nil {
+				cfg.Profiles = map[string]profile{}
+			}
+			p := profile{Repo: url, Branch: addBranch}
+			// 和 --cache-dir 一样先展开 ~ 和环境变量，再保存为绝对路径
+			if addCacheDir != "" {
+				dir, err := expandPath(addCacheDir)
+				if err != nil {
+					return err
+				}
+				if p.CacheDir, err = filepath.Abs(dir); err != nil {
+					return fmt.Errorf("resolving cache directory: %w", err)
+				}
+				if err := checkCacheDir(p.CacheDir); err != nil {
+					return err
+				}
+			}
+			cfg.Profiles[name] = p
+			if err := saveConfig(cfg); err != nil {
+				return err
+			}
+			fmt.Printf("Added repository %s: %s\n", name, url)
+			return nil
+		},
+	}
+	addCmd.Flags().StringVarP(&addBranch, "branch", "b", "", "Branch or tag to clone for this repository")
+	addCmd.Flags().StringVar(&addCacheDir, "cache-dir", "", "Cache directory (default ~/.opencmd/profiles/<name>/commands)")
+
+	var listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List named repositories; the active one is marked with *",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listProfiles()
+		},
+	}
+
+	var useCmd = &cobra.Command{
+		Use:   "use <name>",
+		Short: "Make a named repository the active one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if _, ok := cfg.Profiles[name]; !ok && name != defaultProfile {
+				return fmt.Errorf("unknown profile %q; see 'schema-manager repo list'", name)
+			}
+			cfg.Profile = name
+			if name == defaultProfile {
+				cfg.Profile = ""
+			}
+			if err := saveConfig(cfg); err != nil {
+				return err
+			}
+			fmt.Printf("Now using repository %s\n", name)
+			return nil
+		},
+	}
+
+	repoCmd.AddCommand(addCmd, listCmd, useCmd)
+	return repoCmd
+}
+
+func listProfiles() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	base, err := opencmdDir()
+	if err != nil {
+		return err
+	}
+
+	// default 始终列在最前
+	repo, cache := cfg.Repo, cfg.CacheDir
+	if repo == "" {
+		repo = schemamanager.DefaultRepoURL
+	}
+	if cache == "" {
+		cache = profileCacheDir(base, defaultProfile)
+	}
+	printProfile(defaultProfile, repo, cfg.Branch, cache)
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := cfg.Profiles[name]
+		cache := p.CacheDir
+		if cache == "" {
+			cache = profileCacheDir(base, name)
+		}
+		printProfile(name, p.Repo, p.Branch, cache)
+	}
+	return nil
+}
+
+func printProfile(name, repo, branch, cache string) {
+	mark := " "
+	if name == activeProfile {
+		mark = paint(ansiGreen, "*")
+	}
+	fmt.Printf("%s %s\t%s", mark, name, repo)
+	if branch != "" {
+		fmt.Printf(" (%s)", branch)
+	}
+	fmt.Printf("\t%s\n", cache)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 运行 repo add，返回保存的缓存目录
+func addProfile(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := newRepoCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(append([]string{"add"}, args...))
+	if err := cmd.Execute(); err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Profiles[args[0]].CacheDir, nil
+}
+
+// --cache-dir 中的 ~ 和环境变量在保存前展开，而不是拼到当前目录后面
+func TestRepoAddExpandsCacheDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	data := filepath.Join(t.TempDir(), "data")
+	t.Setenv("XDG_DATA_HOME", data)
+
+	tests := []struct {
+		name, dir, want string
+	}{
+		{"tilde", "~/schemas", filepath.Join(home, "schemas")},
+		{"env", "$XDG_DATA_HOME/opencmd", filepath.Join(data, "opencmd")},
+		{"braced env", "${XDG_DATA_HOME}/work/commands", filepath.Join(data, "work", "commands")},
+	}
+	for _, tt := range tests {
+		got, err := addProfile(t, tt.name, "https://example.com/schemas.git", "--cache-dir", tt.dir)
+		if err != nil {
+			t.Errorf("repo add --cache-dir %s: %v", tt.dir, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("repo add --cache-dir %s stored %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+// 无法创建的缓存目录在保存前报错
+func TestRepoAddRejectsUnusableCacheDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := addProfile(t, "second", "https://example.com/b.git", "--cache-dir", "~/file/commands"); err == nil {
+		t.Error("repo add accepted a cache directory below a regular file")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Profiles["second"]; ok {
+		t.Error("the rejected profile was saved")
+	}
+}
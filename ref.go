@@ -0,0 +1,25 @@
+package main
+
+import "fmt"
+
+// list、search、show 和 stats 的 --ref：从这个版本的提交树中读取文件，不读工作区
+var atRef string
+
+// 检查 --ref 能否和其他参数一起使用。提交树中没有文件系统信息，修改时间只是提交时间
+func checkRef() error {
+	if atRef == "" {
+		return nil
+	}
+	if err := requireGit("--ref"); err != nil {
+		return err
+	}
+	switch {
+	case watchMode:
+		return fmt.Errorf("--ref cannot be combined with --watch: a commit does not change")
+	case since != "":
+		return fmt.Errorf("--ref cannot be combined with --since")
+	case sortKey == "modtime":
+		return fmt.Errorf("--sort modtime is not available with --ref: files read from a commit have no modification time")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"schema-manager/schemamanager"
+)
+
+// search --replace 的替换文本，--write 时把替换写回文件
+var (
+	replaceStr string
+	writeBack  bool
+)
+
+// 预览或执行 search --replace：每个有改动的文件输出一段统一格式的差异
+func replaceMatches(pattern string) error {
+	if !searchBody {
+		return errors.New("--replace requires --content")
+	}
+	opts := schemamanager.SearchOptions{
+		Content:    true,
+		IgnoreCase: ignoreCase,
+		Fixed:      fixedStr,
+		Glob:       globMatch,
+	}
+	result, err := newManager().Replace(pattern, replaceStr, opts, writeBack)
+	if err != nil {
+		return err
+	}
+
+	if outputFmt == "json" {
+		if result == nil {
+			result = []schemamanager.Replacement{}
+		}
+		if err := writeStructured(result); err != nil {
+			return err
+		}
+	} else {
+		count := 0
+		for _, r := range result {
+			fmt.Print(r.Patch)
+			count += r.Count
+		}
+		switch {
+		case len(result) == 0:
+			fmt.Println("No .hl file contents match the pattern.")
+		case writeBack:
+			fmt.Printf("\nReplaced %d match(es) in %d file(s)\n", count, len(result))
+		default:
+			fmt.Printf("\n%d match(es) in %d file(s) would be replaced; run again with --write to apply\n", count, len(result))
+		}
+	}
+	if len(result) == 0 {
+		return &exitError{code: exitNoMatches}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package schemamanager
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/plumbing/transport/http"
+	"github.com/go-git/go-git/v6/plumbing/transport/ssh"
+)
+
+// 按仓库地址选择认证方式：HTTP 使用 Token，SSH 使用用户的默认私钥
+func (m *Manager) auth(url string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ep.Protocol {
+	case "http", "https":
+		if m.Token == "" {
+			return nil, nil
+		}
+		return &http.BasicAuth{Username: "x-access-token", Password: m.Token}, nil
+	case "ssh":
+		user := ep.User
+		if user == "" {
+			user = ssh.DefaultUsername
+		}
+		if key := defaultSSHKey(); key != "" {
+			return ssh.NewPublicKeysFromFile(user, key, "")
+		}
+		// 没有默认私钥时交给 go-git 使用 ssh-agent
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// 查找 ~/.ssh 下的默认私钥，找不到时返回空字符串
+func defaultSSHKey() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		path := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// 从错误信息中去掉 Token 和代理密码，避免泄露到日志
+func (m *Manager) redact(err error) error {
+	if err == nil {
+		return err
+	}
+	msg := err.Error()
+	for _, secret := range m.secrets() {
+		msg = strings.ReplaceAll(msg, secret, "****")
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return redactedError{msg: msg, err: err}
+}
+
+// 不能出现在错误信息中的令牌和代理密码
+func (m *Manager) secrets() []string {
+	var secrets []string
+	if m.Token != "" {
+		secrets = append(secrets, m.Token)
+	}
+	if u, err := url.Parse(m.Proxy); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			secrets = append(secrets, password)
+		}
+	}
+	return secrets
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e redactedError) Error() string { return e.msg }
+
+func (e redactedError) Unwrap() error { return e.err }
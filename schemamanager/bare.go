@@ -0,0 +1,195 @@
+package schemamanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ErrBare 表示操作需要工作区，而缓存是没有工作区的裸仓库
+var ErrBare = errors.New("not available for a bare cache (cloned with 'init --bare')")
+
+// IsBare 报告缓存是否是没有工作区的裸仓库；此时 List、Search 和 ReadFile 从 HEAD 提交的树中读取文件
+func (m *Manager) IsBare() bool {
+	repo, err := git.PlainOpen(m.CacheDir)
+	if err != nil {
+		return false
+	}
+	cfg, err := repo.Config()
+	return err == nil && cfg.Core.IsBare
+}
+
+// 报告读取文件时是否使用提交树而不是工作区：设置了 Ref，或者缓存是裸仓库或没有检出
+func (m *Manager) fromTree() bool {
+	return m.Ref != "" || m.IsBare() || m.IsNoCheckout()
+}
+
+// 读取文件使用的提交：Ref 指定的版本，未设置时为 HEAD
+func (m *Manager) treeCommit(repo *git.Repository) (*object.Commit, error) {
+	if m.Ref != "" {
+		hash, err := resolveCommit(repo, m.Ref)
+		if err != nil {
+			return nil, err
+		}
+		return repo.CommitObject(hash)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("getting HEAD: %w", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("reading HEAD commit: %w", err)
+	}
+	return commit, nil
+}
+
+// Ref 解析到的提交哈希
+func (m *Manager) refHash() (string, error) {
+	repo, err := m.open()
+	if err != nil {
+		return "", err
+	}
+	hash, err := resolveCommit(repo, m.Ref)
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+// 需要工作区的操作在裸仓库中返回 ErrBare，在没有检出的缓存中返回 ErrNoCheckout
+func (m *Manager) requireWorktree(what string) error {
+	if m.IsBare() {
+		return fmt.Errorf("%s is %w", what, ErrBare)
+	}
+	if m.IsNoCheckout() {
+		return fmt.Errorf("%s is %w", what, ErrNoCheckout)
+	}
+	return nil
+}
+
+// 提交树中的一个 .hl 文件
+type treeFile struct {
+	File
+	blob *object.File
+}
+
+// 按 Dir、Exclude、.hlignore、深度和大小过滤提交树中的 .hl 文件，按路径排序；
+// 文件没有修改时间，ModTime 使用该提交的时间
+func (m *Manager) treeFiles() ([]treeFile, error) {
+	if err := checkExclude(m.Exclude); err != nil {
+		return nil, err
+	}
+	repo, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := m.treeCommit(repo)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("reading tree of %s: %w", commit.Hash.String()[:8], err)
+	}
+
+	var all []treeFile
+	err = tree.Files().ForEach(func(f *object.File) error {
+		// 符号链接在树中只是一个路径，不跟随
+		if f.Mode == filemode.Symlink || !m.IsSchemaFile(path.Base(f.Name)) {
+			return nil
+		}
+		if !m.IncludeHidden && hiddenPath(f.Name) {
+			return nil
+		}
+		all = append(all, treeFile{File: File{Path: f.Name, Size: f.Size, ModTime: commit.Committer.When}, blob: f})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading tree of %s: %w", commit.Hash.String()[:8], err)
+	}
+
+	// 复用索引的过滤逻辑，再按保留的路径取回对应的 blob
+	files := make([]File, len(all))
+	byPath := make(map[string]treeFile, len(all))
+	for i, f := range all {
+		files[i] = f.File
+		byPath[f.Path] = f
+	}
+	var kept []treeFile
+	for _, f := range m.filterIndexed(files) {
+		kept = append(kept, byPath[f.Path])
+	}
+	return kept, nil
+}
+
+// 路径中任一段以 . 开头
+func hiddenPath(p string) bool {
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// 逐行匹配提交树中的文件内容，语义和工作区中的按内容搜索相同
+func (m *Manager) searchTree(files []treeFile, regex *regexp.Regexp, opts SearchOptions) ([]Match, error) {
+	var matches []Match
+	for _, f := range files {
+		if opts.Limit > 0 && len(matches) >= opts.Limit {
+			break
+		}
+		r, err := f.blob.Reader()
+		if err != nil {
+			m.warnf("Warning: skipping %s: %v\n", f.Path, err)
+			continue
+		}
+		lines, err := searchReader(r, regex, opts)
+		r.Close()
+		if !m.partialSearch(f.Path, err) {
+			continue
+		}
+		for i := range lines {
+			lines[i].Path = f.Path
+		}
+		matches = append(matches, lines...)
+	}
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+	return matches, nil
+}
+
+// 读取提交树中的一个文件
+func (m *Manager) readTreeFile(relPath string) ([]byte, error) {
+	if _, err := m.Resolve(relPath); err != nil {
+		return nil, err
+	}
+	repo, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := m.treeCommit(repo)
+	if err != nil {
+		return nil, err
+	}
+	f, err := commit.File(path.Clean(filepath.ToSlash(relPath)))
+	// 和读取工作区一样，文件不存在时可以用 errors.Is(err, fs.ErrNotExist) 判断
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, fmt.Errorf("%s: %w", relPath, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", relPath, err)
+	}
+	content, err := f.Contents()
+	return []byte(content), err
+}
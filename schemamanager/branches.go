@@ -0,0 +1,111 @@
+package schemamanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// BranchState 是本地 HEAD 相对一个远程分支的同步状态
+type BranchState string
+
+const (
+	BranchUpToDate BranchState = "up-to-date"
+	BranchBehind   BranchState = "behind"
+	BranchAhead    BranchState = "ahead"
+	BranchDiverged BranchState = "diverged"
+	// BranchUnknown 表示远程分支的提交不在本地，无法计算先后
+	BranchUnknown BranchState = "unknown"
+	// BranchMissing 表示远程没有这个分支
+	BranchMissing BranchState = "missing"
+)
+
+// BranchStatus 是本地 HEAD 和一个远程分支的比较结果
+type BranchStatus struct {
+	Branch     string        `json:"branch"`
+	RemoteHash plumbing.Hash `json:"remoteHash"`
+	State      BranchState   `json:"state"`
+	// Behind 和 Ahead 是远程有而本地没有、本地有而远程没有的提交数，无法计算时为 -1
+	Behind int `json:"behind"`
+	Ahead  int `json:"ahead"`
+}
+
+// BranchesStatus 用一次 ls-remote 查询所有远程分支，逐个和本地 HEAD 比较。
+// 只使用本地已有的提交计算先后，不下载远程提交；远程提交不在本地时状态为 BranchUnknown
+func (m *Manager) BranchesStatus(ctx context.Context, branches []string) (plumbing.Hash, []BranchStatus, error) {
+	repo, err := m.open()
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash, nil, fmt.Errorf("getting HEAD: %w", err)
+	}
+	local := head.Hash()
+
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return local, nil, fmt.Errorf("getting remote: %w", err)
+	}
+	origin := originURL(repo)
+	auth, err := m.auth(origin)
+	if err != nil {
+		return local, nil, fmt.Errorf("preparing credentials: %w", err)
+	}
+	proxy, err := m.proxy(origin)
+	if err != nil {
+		return local, nil, err
+	}
+	insecure := m.insecureTLS(origin)
+	var refs []*plumbing.Reference
+	err = m.retry(ctx, "listing remote refs", func(ctx context.Context) (err error) {
+		refs, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth, ProxyOptions: proxy, InsecureSkipTLS: insecure})
+		return err
+	})
+	if err != nil {
+		return local, nil, fmt.Errorf("listing remote refs: %w", m.remoteErr(err))
+	}
+	m.debugf("remote %s: %d refs\n", origin, len(refs))
+
+	result := make([]BranchStatus, len(branches))
+	for i, name := range branches {
+		s := BranchStatus{Branch: name, Behind: -1, Ahead: -1}
+		s.RemoteHash = findRemoteHash(refs, plumbing.NewBranchReferenceName(name))
+		switch {
+		case s.RemoteHash.IsZero():
+			s.State = BranchMissing
+		case s.RemoteHash == local:
+			s.State, s.Behind, s.Ahead = BranchUpToDate, 0, 0
+		default:
+			s.State = branchState(repo, local, s.RemoteHash, &s)
+		}
+		result[i] = s
+	}
+	return local, result, nil
+}
+
+// 按两边各自独有的提交数判断先后，远程提交不在本地时无法判断
+func branchState(repo *git.Repository, local, remote plumbing.Hash, s *BranchStatus) BranchState {
+	if _, err := repo.CommitObject(remote); err != nil {
+		return BranchUnknown
+	}
+	behind, err := commitsBetween(repo, local, remote)
+	if err != nil {
+		return BranchUnknown
+	}
+	ahead, err := commitsBetween(repo, remote, local)
+	if err != nil {
+		return BranchUnknown
+	}
+	s.Behind, s.Ahead = behind, ahead
+	switch {
+	case ahead == 0:
+		return BranchBehind
+	case behind == 0:
+		return BranchAhead
+	default:
+		return BranchDiverged
+	}
+}
@@ -0,0 +1,65 @@
+package schemamanager
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ChecksumPrefix 是 Checksum 返回的摘要的前缀，表示使用的算法
+const ChecksumPrefix = "sha256:"
+
+// Checksum 计算缓存中所有 .hl 文件的摘要和文件数：按 / 分隔的路径排序，依次写入路径和内容，
+// 各自带长度前缀，避免边界歧义。只依赖文件内容，和 git 历史无关
+func (m *Manager) Checksum() (string, int, error) {
+	if !m.Exists() {
+		return "", 0, ErrNotInitialized
+	}
+	paths, err := m.paths()
+	if err != nil {
+		return "", 0, err
+	}
+	for i, p := range paths {
+		paths[i] = filepath.ToSlash(p)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	writeField := func(b []byte) {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+	}
+	// 裸仓库或指定了 Ref 时读取提交树中的 blob
+	read := func(p string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(m.CacheDir, filepath.FromSlash(p)))
+	}
+	if m.fromTree() {
+		files, err := m.treeFiles()
+		if err != nil {
+			return "", 0, err
+		}
+		blobs := make(map[string]treeFile, len(files))
+		for _, f := range files {
+			blobs[f.Path] = f
+		}
+		read = func(p string) ([]byte, error) {
+			content, err := blobs[p].blob.Contents()
+			return []byte(content), err
+		}
+	}
+	for _, p := range paths {
+		data, err := read(p)
+		if err != nil {
+			return "", 0, fmt.Errorf("reading %s: %w", p, err)
+		}
+		writeField([]byte(p))
+		writeField(data)
+	}
+	return ChecksumPrefix + hex.EncodeToString(h.Sum(nil)), len(paths), nil
+}
@@ -0,0 +1,70 @@
+package schemamanager
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+
+	"schema-manager/schemamanager/hl"
+)
+
+// CommandIndex 解析缓存中的 .hl 文件，返回命令名到声明它的文件路径的映射，路径按顺序排列。
+// 命令名取自文件顶层的 cmd 声明；文件没有声明时（包括无法解析时）按命名约定使用去掉扩展名的文件名
+func (m *Manager) CommandIndex() (map[string][]string, error) {
+	files, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	names, err := parallel(m.jobs(), len(files), func(i int) ([]string, error) {
+		return m.declaredCommands(files[i].Path), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string][]string{}
+	for i, f := range files {
+		for _, name := range names[i] {
+			// 同一个文件重复声明时只记一次
+			if paths := index[name]; len(paths) == 0 || paths[len(paths)-1] != f.Path {
+				index[name] = append(paths, f.Path)
+			}
+		}
+	}
+	for _, paths := range index {
+		sort.Strings(paths)
+	}
+	return index, nil
+}
+
+// FindCommand 返回声明了命令 name 的 .hl 文件，规则和 CommandIndex 相同；没有找到时返回空
+func (m *Manager) FindCommand(name string) ([]string, error) {
+	index, err := m.CommandIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index[name], nil
+}
+
+// 文件中声明的命令名，没有声明时退回文件名
+func (m *Manager) declaredCommands(relPath string) []string {
+	data, err := m.ReadFile(relPath)
+	if err == nil {
+		f, err := hl.Parse(bytes.NewReader(data))
+		if err == nil {
+			if names := f.Commands(); len(names) > 0 {
+				return names
+			}
+		} else {
+			m.debugf("%s: %v; using the file name as the command name\n", relPath, err)
+		}
+	}
+	base := path.Base(relPath)
+	for _, ext := range m.extensions() {
+		if ext != "" && strings.HasSuffix(base, ext) && base != ext {
+			return []string{strings.TrimSuffix(base, ext)}
+		}
+	}
+	return []string{base}
+}
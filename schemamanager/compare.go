@@ -0,0 +1,54 @@
+package schemamanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// CompareResult 是缓存中两个版本之间的 .hl 文件变化
+type CompareResult struct {
+	From plumbing.Hash
+	To   plumbing.Hash
+	// Commits 是 To 有而 From 没有的提交数，无法统计时为 -1
+	Commits int
+	Changes []FileChange
+}
+
+// Compare 在本地比较两个版本，不访问远程；from 和 to 可以是分支、标签、提交或 HEAD~1 这样的写法
+func (m *Manager) Compare(ctx context.Context, from, to string, withPatch bool) (CompareResult, error) {
+	var result CompareResult
+
+	repo, err := m.open()
+	if err != nil {
+		return result, err
+	}
+	if result.From, err = resolveCommit(repo, from); err != nil {
+		return result, err
+	}
+	if result.To, err = resolveCommit(repo, to); err != nil {
+		return result, err
+	}
+
+	result.Commits, err = commitsBetween(repo, result.From, result.To)
+	if err != nil {
+		result.Commits = -1
+	}
+	result.Changes, err = m.fileChanges(ctx, repo, result.From, result.To, withPatch)
+	return result, err
+}
+
+// 把 rev 解析到提交，标签剥离到它指向的提交
+func resolveCommit(repo *git.Repository, rev string) (plumbing.Hash, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("unknown revision %q: not a branch, tag or commit in the cache (run 'fetch' first if it is new)", rev)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("%q is not a commit: %w", rev, err)
+	}
+	return commit.Hash, nil
+}
@@ -0,0 +1,153 @@
+package schemamanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/merkletrie"
+)
+
+// ChangeKind 是文件在两个版本之间的变化类型
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+)
+
+// FileChange 描述一个 .hl 文件在两个版本之间的变化
+type FileChange struct {
+	Path string     `json:"path"`
+	Kind ChangeKind `json:"kind"`
+	// Patch 是统一格式的内容差异，只在请求时填充
+	Patch string `json:"patch,omitempty"`
+}
+
+// DiffResult 是本地 HEAD 和远程跟踪引用之间的 .hl 文件变化
+type DiffResult struct {
+	Ref  plumbing.ReferenceName
+	From plumbing.Hash
+	To   plumbing.Hash
+	// Commits 是本地落后远程的提交数，无法统计时为 -1
+	Commits int
+	Changes []FileChange
+}
+
+// Diff 下载远程跟踪的引用但不合并，列出本地 HEAD 到远程之间变化的 .hl 文件
+func (m *Manager) Diff(ctx context.Context, withPatch bool) (DiffResult, error) {
+	var result DiffResult
+
+	repo, err := m.open()
+	if err != nil {
+		return result, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return result, fmt.Errorf("getting HEAD: %w", err)
+	}
+	result.From = head.Hash()
+
+	auth, err := m.auth(originURL(repo))
+	if err != nil {
+		return result, fmt.Errorf("preparing credentials: %w", err)
+	}
+	proxy, err := m.proxy(originURL(repo))
+	if err != nil {
+		return result, err
+	}
+	insecure := m.insecureTLS(originURL(repo))
+
+	// 下载到远程跟踪引用，工作区保持不变
+	result.Ref = trackedRef(repo)
+	err = m.retry(ctx, "fetching", func(ctx context.Context) error {
+		return fetchTracked(ctx, repo, result.Ref, auth, proxy, insecure)
+	})
+	if err != nil {
+		return result, fmt.Errorf("fetching remote: %w", m.remoteErr(err))
+	}
+
+	dst := plumbing.NewRemoteReferenceName("origin", result.Ref.Short())
+	if result.Ref.IsTag() {
+		dst = result.Ref
+	}
+	// 附注标签需要剥离到提交
+	to, err := repo.ResolveRevision(plumbing.Revision(dst.String()))
+	if err != nil {
+		return result, fmt.Errorf("resolving remote %s: %w", result.Ref.Short(), err)
+	}
+	result.To = *to
+
+	result.Commits, err = commitsBetween(repo, result.From, result.To)
+	if err != nil {
+		result.Commits = -1
+	}
+
+	result.Changes, err = m.fileChanges(ctx, repo, result.From, result.To, withPatch)
+	return result, err
+}
+
+// 比较两个提交的文件树，只保留 .hl 文件的变化，withPatch 时附带内容差异
+func (m *Manager) fileChanges(ctx context.Context, repo *git.Repository, from, to plumbing.Hash, withPatch bool) ([]FileChange, error) {
+	changes, err := diffTrees(repo, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("comparing trees: %w", err)
+	}
+
+	var result []FileChange
+	for _, c := range changes {
+		action, err := c.Action()
+		if err != nil {
+			return nil, err
+		}
+
+		fc := FileChange{Path: c.To.Name}
+		switch action {
+		case merkletrie.Insert:
+			fc.Kind = Added
+		case merkletrie.Delete:
+			fc.Kind = Deleted
+			fc.Path = c.From.Name
+		default:
+			fc.Kind = Modified
+		}
+		if !m.IsSchemaFile(fc.Path) {
+			continue
+		}
+
+		if withPatch {
+			patch, err := c.PatchContext(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("diffing %s: %w", fc.Path, err)
+			}
+			fc.Patch = patch.String()
+		}
+		result = append(result, fc)
+	}
+	return result, nil
+}
+
+// 比较两个提交的文件树
+func diffTrees(repo *git.Repository, from, to plumbing.Hash) (object.Changes, error) {
+	fromCommit, err := repo.CommitObject(from)
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := repo.CommitObject(to)
+	if err != nil {
+		return nil, err
+	}
+	fromTree, err := fromCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := toCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return object.DiffTree(fromTree, toTree)
+}
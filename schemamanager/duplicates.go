@@ -0,0 +1,36 @@
+package schemamanager
+
+import (
+	"path"
+	"sort"
+)
+
+// Duplicate 是出现在多个目录中的同名 .hl 文件
+type Duplicate struct {
+	Name  string   `json:"name"`
+	Paths []string `json:"paths"`
+}
+
+// Duplicates 按文件名把 .hl 文件分组，返回出现在不止一个目录中的文件名及其全部路径，按文件名排序。
+// 按文件名查找命令时这些文件会互相冲突
+func (m *Manager) Duplicates() ([]Duplicate, error) {
+	files, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string][]string{}
+	for _, f := range files {
+		name := path.Base(f.Path)
+		byName[name] = append(byName[name], f.Path)
+	}
+
+	var result []Duplicate
+	for name, paths := range byName {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			result = append(result, Duplicate{Name: name, Paths: paths})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
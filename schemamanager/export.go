@@ -0,0 +1,85 @@
+package schemamanager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ExportOptions 控制 Export 的复制方式
+type ExportOptions struct {
+	// Dest 是目标目录，不存在时创建
+	Dest string
+	// Flatten 为 true 时所有文件直接放在 Dest 下，否则保留相对缓存的目录结构
+	Flatten bool
+	// Force 允许覆盖已存在的文件
+	Force bool
+}
+
+// Export 把缓存中的 paths 复制到 opts.Dest，返回复制的文件数；复制前先检查冲突，不会只复制一部分
+func (m *Manager) Export(paths []string, opts ExportOptions) (int, error) {
+	if !m.Exists() {
+		return 0, ErrNotInitialized
+	}
+	if err := m.requireWorktree("export"); err != nil {
+		return 0, err
+	}
+
+	// 先确定每个文件的目标位置
+	type copyJob struct{ src, dst string }
+	jobs := make([]copyJob, 0, len(paths))
+	sources := make(map[string]string, len(paths))
+	for _, p := range paths {
+		src, err := m.Resolve(p)
+		if err != nil {
+			return 0, err
+		}
+		rel := p
+		if opts.Flatten {
+			rel = filepath.Base(p)
+		}
+		dst := filepath.Join(opts.Dest, rel)
+		if other, ok := sources[dst]; ok {
+			return 0, fmt.Errorf("%s and %s would both be exported as %s", other, p, dst)
+		}
+		if !opts.Force {
+			if _, err := os.Stat(dst); err == nil {
+				return 0, fmt.Errorf("%s already exists; use --force to overwrite", dst)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return 0, err
+			}
+		}
+		sources[dst] = p
+		jobs = append(jobs, copyJob{src: src, dst: dst})
+	}
+
+	for i, job := range jobs {
+		if err := copyFile(job.src, job.dst); err != nil {
+			return i, fmt.Errorf("exporting %s: %w", paths[i], err)
+		}
+	}
+	return len(jobs), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
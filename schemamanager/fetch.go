@@ -0,0 +1,88 @@
+package schemamanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+)
+
+// RefUpdate 是 Fetch 改变的一个远程跟踪引用；新增的引用 Old 为零值
+type RefUpdate struct {
+	Name plumbing.ReferenceName
+	Old  plumbing.Hash
+	New  plumbing.Hash
+}
+
+// Fetch 按 origin 配置的 refspec 下载远程的新提交，只更新远程跟踪引用和标签，不改动工作区和 HEAD；
+// 返回发生变化的引用，已经是最新时为空
+func (m *Manager) Fetch(ctx context.Context) ([]RefUpdate, error) {
+	repo, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := m.auth(originURL(repo))
+	if err != nil {
+		return nil, fmt.Errorf("preparing credentials: %w", err)
+	}
+	proxy, err := m.proxy(originURL(repo))
+	if err != nil {
+		return nil, err
+	}
+	insecure := m.insecureTLS(originURL(repo))
+
+	before, err := remoteRefs(repo)
+	if err != nil {
+		return nil, err
+	}
+	err = m.retry(ctx, "fetching", func(ctx context.Context) error {
+		return repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName:      "origin",
+			Auth:            auth,
+			ProxyOptions:    proxy,
+			InsecureSkipTLS: insecure,
+			Progress:        m.Progress,
+		})
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching remote: %w", m.remoteErr(err))
+	}
+
+	after, err := remoteRefs(repo)
+	if err != nil {
+		return nil, err
+	}
+	var updates []RefUpdate
+	for name, hash := range after {
+		if before[name] != hash {
+			updates = append(updates, RefUpdate{Name: name, Old: before[name], New: hash})
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Name < updates[j].Name })
+	return updates, nil
+}
+
+// 远程跟踪引用和标签当前指向的哈希
+func remoteRefs(repo *git.Repository) (map[plumbing.ReferenceName]plumbing.Hash, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("listing references: %w", err)
+	}
+	refs := map[plumbing.ReferenceName]plumbing.Hash{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if (ref.Name().IsRemote() || ref.Name().IsTag()) && ref.Type() == plumbing.HashReference {
+			refs[ref.Name()] = ref.Hash()
+		}
+		return nil
+	})
+	if err != nil && err != storer.ErrStop {
+		return nil, err
+	}
+	return refs, nil
+}
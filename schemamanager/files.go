@@ -0,0 +1,532 @@
+package schemamanager
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// File 描述缓存中的一个 .hl 文件，Path 是相对缓存目录的路径，在所有平台上都以 / 分隔
+type File struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// Match 是一条搜索结果，Path 同 File.Path 以 / 分隔；文件名匹配时 Line 和 Column 为 0
+type Match struct {
+	Path string `json:"path"`
+	Line int    `json:"line,omitempty"`
+	// Column 是行内第一处匹配的起始列，从 1 开始按 UTF-8 字符而不是字节计数
+	Column int    `json:"column,omitempty"`
+	Text   string `json:"text,omitempty"`
+	// Score 是模糊匹配的得分，越高越相关
+	Score int `json:"score,omitempty"`
+	// Before 和 After 是内容搜索时匹配行前后的上下文行，行数由 SearchOptions 的 Before 和 After 决定，
+	// 在文件开头和结尾处会更少；相邻匹配的上下文可能互相重叠，也可能包含其他匹配行
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// SearchOptions 控制 Search 的匹配方式
+type SearchOptions struct {
+	// Content 为 true 时逐行匹配文件内容，否则只匹配文件名
+	Content bool
+	// IgnoreCase 忽略大小写
+	IgnoreCase bool
+	// Fixed 把模式当作普通字符串而不是正则表达式
+	Fixed bool
+	// FullPath 让文件名匹配改为匹配以 / 分隔的相对路径
+	FullPath bool
+	// Glob 把模式当作 shell glob 匹配整个文件名或路径：* 和 ? 不跨越 /，** 可以跨越目录
+	Glob bool
+	// Fuzzy 按子序列模糊匹配文件名，结果按得分从高到低排列，不使用正则
+	Fuzzy bool
+	// Invert 反转匹配：按文件名搜索时返回不匹配的文件，按内容搜索时返回不匹配的行，此时 Column 为 0
+	Invert bool
+	// Limit 大于 0 时最多返回 Limit 条匹配：模糊匹配取得分最高的，其余按路径顺序取最前面的；
+	// 按内容搜索时找到足够的匹配后不再读取后面的文件
+	Limit int
+	// Before 和 After 是内容搜索时每个匹配行之前和之后附带的上下文行数
+	Before int
+	After  int
+}
+
+var errBinaryFile = errors.New("binary file")
+
+// 逐行读取时单行的最大长度，bufio.Scanner 默认只有 64 KB
+const maxLineLength = 16 << 20
+
+// partialReadError 表示读到 Line 行时失败（例如行超过 maxLineLength），之前的内容已经处理
+type partialReadError struct {
+	Line int
+	Err  error
+}
+
+func (e *partialReadError) Error() string {
+	return fmt.Sprintf("stopped reading at line %d: %v", e.Line, e.Err)
+}
+
+func (e *partialReadError) Unwrap() error { return e.Err }
+
+// 扫描 r 的每一行，单行可以长到 maxLineLength
+func lineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	return scanner
+}
+
+// 扫描结束后的错误，带上读到的行号
+func scanErr(scanner *bufio.Scanner, line int) error {
+	if err := scanner.Err(); err != nil {
+		return &partialReadError{Line: line, Err: err}
+	}
+	return nil
+}
+
+// 找到足够的匹配后用来停止分发任务
+var errLimitReached = errors.New("search limit reached")
+
+// ErrInvalidPattern 表示搜索模式无法使用，errors.Is 对所有 *PatternError 成立
+var ErrInvalidPattern = errors.New("invalid search pattern")
+
+// PatternError 表示搜索模式无法编译
+type PatternError struct {
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("invalid search pattern: %v", e.Err)
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PatternError) Is(target error) bool {
+	return target == ErrInvalidPattern
+}
+
+// List 返回缓存中所有 .hl 文件，按路径排序；设置了 Ref 或者是裸仓库时列出提交树中的文件
+func (m *Manager) List() ([]File, error) {
+	if !m.Exists() {
+		return nil, ErrNotInitialized
+	}
+
+	// 裸仓库没有工作区，和指定了 Ref 时一样从提交树中读取
+	if m.fromTree() {
+		tree, err := m.treeFiles()
+		if err != nil {
+			return nil, err
+		}
+		files := make([]File, len(tree))
+		for i, f := range tree {
+			files[i] = f.File
+		}
+		return files, nil
+	}
+
+	// 索引可用时不遍历目录
+	if m.IndexPath != "" {
+		idx, err := m.currentIndex()
+		if err != nil {
+			return nil, err
+		}
+		if idx != nil {
+			return m.filterIndexed(idx.Files), nil
+		}
+	}
+
+	entries, err := m.collect()
+	if err != nil {
+		return nil, err
+	}
+	return m.stat(entries)
+}
+
+// Walk 和 List 选出同样的文件，但每找到一个就调用 fn，不在内存中保留整个列表；
+// 遍历目录时按路径顺序边走边调用。fn 返回错误时停止并返回该错误
+func (m *Manager) Walk(fn func(File) error) error {
+	if !m.Exists() {
+		return ErrNotInitialized
+	}
+
+	// 提交树和索引本来就在内存中，逐个交给 fn 即可
+	each := func(files []File) error {
+		for _, f := range files {
+			if err := fn(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if m.fromTree() {
+		files, err := m.List()
+		if err != nil {
+			return err
+		}
+		return each(files)
+	}
+	if m.IndexPath != "" {
+		idx, err := m.currentIndex()
+		if err != nil {
+			return err
+		}
+		if idx != nil {
+			return each(m.filterIndexed(idx.Files))
+		}
+	}
+
+	root, err := m.walkRoot()
+	if err != nil {
+		return err
+	}
+	rules, err := m.ignoreRules()
+	if err != nil {
+		return err
+	}
+	return m.visit(root, m.Exclude, rules, m.maxDepth(), func(e entry) error {
+		// 和 collect 一样，读取不到信息的文件跳过
+		info, err := e.d.Info()
+		if err != nil {
+			m.warnf("Warning: skipping %s: %v\n", e.relPath, err)
+			return nil
+		}
+		if !m.sizeOK(info.Size()) {
+			return nil
+		}
+		return fn(File{Path: e.relPath, Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
+
+// Search 返回匹配正则 pattern 的 .hl 文件或文件内容行
+func (m *Manager) Search(pattern string, opts SearchOptions) ([]Match, error) {
+	if !m.Exists() {
+		return nil, ErrNotInitialized
+	}
+
+	if opts.Fuzzy {
+		if opts.Content {
+			return nil, errors.New("fuzzy matching only applies to file names, not contents")
+		}
+		if opts.Invert {
+			return nil, errors.New("fuzzy matching cannot be inverted")
+		}
+		paths, err := m.paths()
+		if err != nil {
+			return nil, err
+		}
+		return fuzzySearch(paths, pattern, opts), nil
+	}
+
+	regex, err := CompilePattern(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按文件名搜索时只需要路径，可以直接使用索引
+	if !opts.Content {
+		paths, err := m.paths()
+		if err != nil {
+			return nil, err
+		}
+		var matches []Match
+		for _, p := range paths {
+			if opts.Limit > 0 && len(matches) >= opts.Limit {
+				break
+			}
+			if regex.MatchString(nameSubject(p, opts)) != opts.Invert {
+				matches = append(matches, Match{Path: p})
+			}
+		}
+		return matches, nil
+	}
+
+	if m.fromTree() {
+		files, err := m.treeFiles()
+		if err != nil {
+			return nil, err
+		}
+		return m.searchTree(files, regex, opts)
+	}
+
+	entries, err := m.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	// 每个文件的匹配在工作协程中完成，结果按遍历顺序（路径排序）拼接；
+	// 达到 Limit 后不再分发新文件，已分发的文件都会读完，所以结果仍是路径顺序的前缀
+	var found atomic.Int64
+	perFile, err := parallel(m.jobs(), len(entries), func(i int) ([]Match, error) {
+		e := entries[i]
+
+		// 逐行匹配内容
+		lines, err := searchContent(e.path, regex, opts)
+		if !m.partialSearch(e.relPath, err) {
+			return nil, nil
+		}
+		for j := range lines {
+			lines[j].Path = e.relPath
+		}
+		if opts.Limit > 0 && found.Add(int64(len(lines))) >= int64(opts.Limit) {
+			return lines, errLimitReached
+		}
+		return lines, nil
+	})
+	if err != nil && err != errLimitReached {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, ms := range perFile {
+		matches = append(matches, ms...)
+	}
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+	return matches, nil
+}
+
+// 缓存中 .hl 文件的相对路径，索引可用时不遍历目录
+func (m *Manager) paths() ([]string, error) {
+	if m.fromTree() {
+		files, err := m.List()
+		if err != nil {
+			return nil, err
+		}
+		paths := make([]string, len(files))
+		for i, f := range files {
+			paths[i] = f.Path
+		}
+		return paths, nil
+	}
+	if m.IndexPath != "" {
+		idx, err := m.currentIndex()
+		if err != nil {
+			return nil, err
+		}
+		if idx != nil {
+			files := m.filterIndexed(idx.Files)
+			paths := make([]string, len(files))
+			for i, f := range files {
+				paths[i] = f.Path
+			}
+			return paths, nil
+		}
+	}
+
+	entries, err := m.collect()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		paths[i] = e.relPath
+	}
+	return paths, nil
+}
+
+// 文件名匹配的对象：默认只是文件名部分，路径统一用 / 分隔，保证各平台的模式一致
+func nameSubject(relPath string, opts SearchOptions) string {
+	if opts.FullPath {
+		return filepath.ToSlash(relPath)
+	}
+	return filepath.Base(relPath)
+}
+
+// Resolve 把 list 输出的相对路径解析为缓存中的绝对路径，拒绝通过 .. 或符号链接逃出缓存的路径
+func (m *Manager) Resolve(relPath string) (string, error) {
+	if filepath.IsAbs(relPath) {
+		return "", fmt.Errorf("path %q must be relative to the cache directory", relPath)
+	}
+
+	path := filepath.Join(m.CacheDir, filepath.FromSlash(relPath))
+	if !within(m.CacheDir, path) {
+		return "", fmt.Errorf("path %q escapes the cache directory", relPath)
+	}
+
+	// 符号链接解析后仍然必须位于缓存内
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		root, err := filepath.EvalSymlinks(m.CacheDir)
+		if err != nil {
+			return "", err
+		}
+		if !within(root, real) {
+			return "", fmt.Errorf("path %q escapes the cache directory", relPath)
+		}
+	}
+	return path, nil
+}
+
+// ReadFile 读取缓存中的一个文件，设置了 Ref 或者是裸仓库时读取提交树中的版本
+func (m *Manager) ReadFile(relPath string) ([]byte, error) {
+	if !m.Exists() {
+		return nil, ErrNotInitialized
+	}
+	if m.fromTree() {
+		return m.readTreeFile(relPath)
+	}
+	path, err := m.Resolve(relPath)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// 报告 path 是否位于 root 之内
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// CompilePattern 按选项把搜索模式编译成 Search 使用的正则，Fixed 和 IgnoreCase 可以组合使用
+func CompilePattern(pattern string, opts SearchOptions) (*regexp.Regexp, error) {
+	expr := pattern
+	if opts.Glob {
+		if opts.Fixed || opts.Fuzzy {
+			return nil, &PatternError{Pattern: pattern, Err: errors.New("glob cannot be combined with fixed-string or fuzzy matching")}
+		}
+		if opts.Content {
+			return nil, &PatternError{Pattern: pattern, Err: errors.New("glob patterns apply to file names and paths, not contents")}
+		}
+		// path.Match 负责检查 glob 语法，例如未闭合的 [
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, &PatternError{Pattern: pattern, Err: err}
+		}
+		expr = globRegexp(pattern)
+	}
+	if opts.Fixed {
+		if pattern == "" {
+			return nil, &PatternError{Pattern: pattern, Err: errors.New("fixed-string pattern must not be empty")}
+		}
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, &PatternError{Pattern: pattern, Err: err}
+	}
+	return regex, nil
+}
+
+// 把 glob 转换成匹配整个字符串的正则
+func globRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") {
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(glob[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			// 字符类原样保留，[!...] 转换为 [^...]
+			end := strings.IndexByte(glob[i+1:], ']')
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// 逐行扫描文件内容，避免把整个文件读入内存
+func searchContent(path string, regex *regexp.Regexp, opts SearchOptions) ([]Match, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return searchReader(f, regex, opts)
+}
+
+// 逐行匹配 r 的内容，opts.Invert 时返回不匹配的行，并按 opts.Before 和 opts.After 附带上下文；
+// 开头含有 NUL 字节时视为二进制文件。读到一半失败时返回已经找到的匹配和 *partialReadError
+func searchReader(r io.Reader, regex *regexp.Regexp, opts SearchOptions) ([]Match, error) {
+	reader := bufio.NewReader(r)
+	// 文件开头含有 NUL 字节时视为二进制文件
+	head, _ := reader.Peek(512)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, errBinaryFile
+	}
+
+	var matches []Match
+	// 最近的 opts.Before 行，作为下一个匹配之前的上下文
+	var recent []string
+	scanner := lineScanner(reader)
+	line := 1
+	for ; scanner.Scan(); line++ {
+		text := scanner.Text()
+		// 补上前面 opts.After 行以内的匹配之后的上下文
+		for i := len(matches) - 1; i >= 0 && matches[i].Line >= line-opts.After; i-- {
+			matches[i].After = append(matches[i].After, text)
+		}
+
+		loc := regex.FindStringIndex(text)
+		switch {
+		case opts.Invert && loc == nil:
+			matches = append(matches, Match{Line: line, Text: text})
+		case !opts.Invert && loc != nil:
+			matches = append(matches, Match{Line: line, Column: utf8.RuneCountInString(text[:loc[0]]) + 1, Text: text})
+		}
+		if n := len(matches); n > 0 && matches[n-1].Line == line && len(recent) > 0 {
+			matches[n-1].Before = append([]string(nil), recent...)
+		}
+		if opts.Before > 0 {
+			if len(recent) == opts.Before {
+				recent = recent[1:]
+			}
+			recent = append(recent, text)
+		}
+	}
+	return matches, scanErr(scanner, line)
+}
+
+// 处理一个文件内容搜索的错误，返回是否使用已经找到的匹配：读到一半失败时给出警告并保留之前的匹配，
+// 其他错误跳过整个文件
+func (m *Manager) partialSearch(relPath string, err error) bool {
+	var partial *partialReadError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &partial):
+		m.warnf("Warning: %s: %v; the rest of the file was not searched\n", relPath, err)
+		return true
+	}
+	m.warnf("Warning: skipping %s: %v\n", relPath, err)
+	return false
+}
@@ -0,0 +1,97 @@
+package schemamanager
+
+import (
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// 测试用的本地源仓库，commit 写入文件并提交
+type fixtureRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+	n    int
+}
+
+func newFixtureRepo(t *testing.T) *fixtureRepo {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "src")
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("init fixture: %v", err)
+	}
+	return &fixtureRepo{t: t, dir: dir, repo: repo}
+}
+
+// 写入 files（相对路径 → 内容）并提交，返回新提交
+func (f *fixtureRepo) commit(files map[string]string) plumbing.Hash {
+	f.t.Helper()
+	w, err := f.repo.Worktree()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(f.dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			f.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			f.t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			f.t.Fatalf("add %s: %v", name, err)
+		}
+	}
+	f.n++
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000+int64(f.n)*60, 0)}
+	hash, err := w.Commit("commit "+string(rune('0'+f.n)), &git.CommitOptions{Author: sig, AllowEmptyCommits: true})
+	if err != nil {
+		f.t.Fatalf("commit: %v", err)
+	}
+	return hash
+}
+
+// 克隆 f 到新的缓存目录，depth 大于 0 时通过 serve 的地址浅克隆
+func (f *fixtureRepo) clone(t *testing.T, depth int) *Manager {
+	t.Helper()
+	m := New(filepath.Join(t.TempDir(), "cache"))
+	m.RepoURL = f.dir
+	if depth > 0 {
+		m.RepoURL = f.serve(t)
+	}
+	m.Depth = depth
+	if err := m.Clone(t.Context(), ""); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	return m
+}
+
+// 通过 git http-backend 提供 f 的智能 HTTP 地址。go-git 的本地传输不支持浅克隆，
+// 需要浅克隆的测试使用这个地址；没有安装 git 时跳过
+func (f *fixtureRepo) serve(t *testing.T) string {
+	t.Helper()
+	out, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	backend := filepath.Join(strings.TrimSpace(string(out)), "git-http-backend")
+	if _, err := os.Stat(backend); err != nil {
+		t.Skip("git-http-backend is not available")
+	}
+	srv := httptest.NewServer(&cgi.Handler{
+		Path: backend,
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(f.dir), "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(srv.Close)
+	return srv.URL + "/" + filepath.Base(f.dir) + "/.git"
+}
@@ -0,0 +1,80 @@
+package schemamanager
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 模糊匹配的加分项，和 fzf 的思路类似：连续匹配和单词开头匹配更相关
+const (
+	scoreMatch       = 16
+	bonusConsecutive = 8
+	bonusBoundary    = 10
+	bonusCamel       = 7
+	penaltyGap       = 2
+)
+
+// 按子序列模糊匹配路径，结果按得分从高到低排列，得分相同时按路径排序
+func fuzzySearch(paths []string, pattern string, opts SearchOptions) []Match {
+	var matches []Match
+	for _, p := range paths {
+		if score, ok := fuzzyScore(pattern, nameSubject(p, opts)); ok {
+			matches = append(matches, Match{Path: p, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+	return matches
+}
+
+// 计算 pattern 作为 s 的子序列时的得分，不区分大小写；不是子序列时返回 false
+func fuzzyScore(pattern, s string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	text := []rune(s)
+	lower := []rune(strings.ToLower(s))
+	if len(lower) != len(text) {
+		lower = text
+	}
+
+	score, pi, prev := 0, 0, -1
+	for i := 0; i < len(lower) && pi < len(p); i++ {
+		if lower[i] != p[pi] {
+			continue
+		}
+		score += scoreMatch
+		switch {
+		case i == 0 || isBoundary(text[i-1]):
+			score += bonusBoundary
+		case unicode.IsUpper(text[i]) && unicode.IsLower(text[i-1]):
+			score += bonusCamel
+		}
+		if prev >= 0 {
+			if i == prev+1 {
+				score += bonusConsecutive
+			} else {
+				score -= penaltyGap * min(i-prev-1, 4)
+			}
+		}
+		prev = i
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	// 同样的匹配，较短的名字更相关
+	return score - len(text)/4, true
+}
+
+func isBoundary(r rune) bool {
+	switch r {
+	case '/', '-', '_', '.', ' ':
+		return true
+	}
+	return false
+}
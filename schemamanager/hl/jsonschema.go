@@ -0,0 +1,437 @@
+package hl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDraft 是生成的文档声明的 JSON Schema 版本
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// ToJSONSchema 把 .hl 内容转换为描述命令调用的 JSON Schema 文档，缩进两个空格。
+//
+// 每条 cmd（或 command）声明是一个对象：flags 属性描述 flag（或 option）语句，args 是按位置排列的
+// arg（或 argument）语句，commands 是嵌套的子命令，一次只能选择一个；description（或 desc）语句
+// 是说明。flag 和 arg 语句的形式是
+//
+//	flag NAME[?|!][:] [TYPE] [= DEFAULT] ["说明"] [required|optional]
+//
+// TYPE 可以是 bool、string、int、float、path、file、dir、url、duration、enum(a, b)，前面加 []
+// 表示数组，后面加 ... 表示可重复。flag 默认可选、类型为 bool；arg 默认必需、类型为 string，
+// 只有最后一个 arg 可以重复。文件只有一个命令时文档就是该命令，有多个时放在 $defs 中由 oneOf 引用；
+// 头部元数据的 description、version 和 target 写入文档。其他语句被忽略，无法识别的类型或多余的
+// 词法单元返回 *SyntaxError
+func ToJSONSchema(src []byte) ([]byte, error) {
+	f, err := Parse(strings.NewReader(string(src)))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := f.jsonSchema(ParseMeta(src))
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (f *File) jsonSchema(meta Meta) (map[string]any, error) {
+	var names []string
+	schemas := map[string]any{}
+	for _, s := range f.Statements {
+		if !isCommand(s) {
+			continue
+		}
+		schema, err := commandSchema(s)
+		if err != nil {
+			return nil, err
+		}
+		name := schema["title"].(string)
+		if _, dup := schemas[name]; dup {
+			return nil, &SyntaxError{Line: s.Line, Col: s.Tokens[0].Col, Msg: fmt.Sprintf("command %q is declared more than once", name)}
+		}
+		names = append(names, name)
+		schemas[name] = schema
+	}
+
+	var doc map[string]any
+	switch len(names) {
+	case 0:
+		return nil, fmt.Errorf("no cmd declaration to translate")
+	case 1:
+		doc = schemas[names[0]].(map[string]any)
+	default:
+		refs := make([]any, len(names))
+		for i, name := range names {
+			refs[i] = map[string]any{"$ref": "#/$defs/" + name}
+		}
+		doc = map[string]any{"$defs": schemas, "oneOf": refs}
+	}
+	doc["$schema"] = JSONSchemaDraft
+	if _, ok := doc["description"]; !ok && meta.Description != "" {
+		doc["description"] = meta.Description
+	}
+	if meta.Version != "" {
+		doc["x-version"] = meta.Version
+	}
+	if meta.Target != "" {
+		doc["x-target"] = meta.Target
+	}
+	return doc, nil
+}
+
+func isCommand(s *Statement) bool {
+	return len(s.Tokens) > 0 && s.Tokens[0].Kind == Ident && commandKeywords[s.Tokens[0].Text]
+}
+
+// 语句的关键字，不区分大小写
+func keyword(s *Statement) string {
+	if len(s.Tokens) == 0 || s.Tokens[0].Kind != Ident {
+		return ""
+	}
+	return strings.ToLower(s.Tokens[0].Text)
+}
+
+// 一条 cmd 声明及其语句块对应的对象
+func commandSchema(s *Statement) (map[string]any, error) {
+	if len(s.Tokens) < 2 || (s.Tokens[1].Kind != Ident && s.Tokens[1].Kind != String) {
+		return nil, tokenError(s, 0, "expected a command name")
+	}
+	if len(s.Tokens) > 2 {
+		return nil, tokenError(s, 2, "unexpected %q after command name", s.Tokens[2].Text)
+	}
+	name := s.Tokens[1].Text
+	schema := map[string]any{"title": name, "type": "object", "additionalProperties": false}
+
+	flags := map[string]any{}
+	var requiredFlags []any
+	var args []any
+	requiredArgs := 0
+	var variadic map[string]any
+	subs := map[string]any{}
+	for _, st := range s.Body {
+		switch keyword(st) {
+		case "flag", "option", "opt":
+			p, err := parseParam(st, false)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := flags[p.name]; dup {
+				return nil, tokenError(st, 1, "flag %q is declared more than once in command %s", p.name, name)
+			}
+			flags[p.name] = p.schema
+			if p.required {
+				requiredFlags = append(requiredFlags, p.name)
+			}
+		case "arg", "argument":
+			p, err := parseParam(st, true)
+			if err != nil {
+				return nil, err
+			}
+			if variadic != nil {
+				return nil, tokenError(st, 1, "argument %q follows a repeated argument", p.name)
+			}
+			// 必需的参数必须排在可选参数之前，否则 minItems 无法表达
+			if p.required {
+				if requiredArgs < len(args) {
+					return nil, tokenError(st, 1, "required argument %q follows an optional one", p.name)
+				}
+				requiredArgs++
+			}
+			p.schema["title"] = p.name
+			if p.variadic {
+				variadic = p.schema
+			} else {
+				args = append(args, p.schema)
+			}
+		case "cmd", "command":
+			sub, err := commandSchema(st)
+			if err != nil {
+				return nil, err
+			}
+			subName := sub["title"].(string)
+			if _, dup := subs[subName]; dup {
+				return nil, tokenError(st, 1, "subcommand %q is declared more than once in command %s", subName, name)
+			}
+			subs[subName] = sub
+		case "description", "desc":
+			if len(st.Tokens) != 2 {
+				return nil, tokenError(st, 0, "description takes a single string")
+			}
+			schema["description"] = st.Tokens[1].Text
+		}
+	}
+
+	props := map[string]any{}
+	var required []any
+	if len(flags) > 0 {
+		obj := map[string]any{"type": "object", "properties": flags, "additionalProperties": false}
+		if len(requiredFlags) > 0 {
+			obj["required"] = requiredFlags
+			required = append(required, "flags")
+		}
+		props["flags"] = obj
+	}
+	if len(args) > 0 || variadic != nil {
+		arr := map[string]any{"type": "array", "minItems": requiredArgs}
+		if len(args) > 0 {
+			arr["prefixItems"] = args
+		}
+		if variadic != nil {
+			arr["items"] = variadic
+		} else {
+			arr["items"] = false
+		}
+		props["args"] = arr
+		if requiredArgs > 0 {
+			required = append(required, "args")
+		}
+	}
+	if len(subs) > 0 {
+		props["commands"] = map[string]any{"type": "object", "properties": subs, "additionalProperties": false, "maxProperties": 1}
+	}
+	if len(props) > 0 {
+		schema["properties"] = props
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+type param struct {
+	name     string
+	schema   map[string]any
+	required bool
+	variadic bool
+}
+
+// 解析 flag 或 arg 语句
+func parseParam(s *Statement, isArg bool) (param, error) {
+	toks := s.Tokens
+	kind := "flag"
+	if isArg {
+		kind = "argument"
+	}
+	if len(toks) < 2 || (toks[1].Kind != Ident && toks[1].Kind != String) {
+		return param{}, tokenError(s, 0, "expected a %s name", kind)
+	}
+	p := param{name: toks[1].Text, required: isArg}
+	i := 2
+	punct := func(text string) bool {
+		if i < len(toks) && toks[i].Kind == Punct && toks[i].Text == text {
+			i++
+			return true
+		}
+		return false
+	}
+
+	switch {
+	case punct("?"):
+		p.required = false
+	case punct("!"):
+		p.required = true
+	}
+	punct(":")
+
+	// 类型
+	var err error
+	p.schema, err = parseType(s, &i, isArg)
+	if err != nil {
+		return param{}, err
+	}
+	if punct(".") {
+		if !punct(".") || !punct(".") {
+			return param{}, tokenError(s, i-1, "expected ... after the type")
+		}
+		if isArg {
+			p.variadic = true
+		} else {
+			p.schema = map[string]any{"type": "array", "items": p.schema}
+		}
+	}
+
+	// 默认值、说明和 required/optional 可以按任意顺序出现
+	for i < len(toks) {
+		t := toks[i]
+		switch {
+		case t.Kind == Punct && t.Text == "=":
+			i++
+			value, err := parseDefault(s, &i, p.schema)
+			if err != nil {
+				return param{}, err
+			}
+			p.schema["default"] = value
+		case t.Kind == String:
+			p.schema["description"] = t.Text
+			i++
+		case t.Kind == Ident && strings.EqualFold(t.Text, "required"):
+			p.required = true
+			i++
+		case t.Kind == Ident && strings.EqualFold(t.Text, "optional"):
+			p.required = false
+			i++
+		default:
+			return param{}, tokenError(s, i, "unexpected %q in %s %s", t.Text, kind, p.name)
+		}
+	}
+	return p, nil
+}
+
+// JSON Schema 中对应的类型，format 为空时不设置
+var typeNames = map[string][2]string{
+	"bool":     {"boolean", ""},
+	"boolean":  {"boolean", ""},
+	"string":   {"string", ""},
+	"str":      {"string", ""},
+	"text":     {"string", ""},
+	"int":      {"integer", ""},
+	"integer":  {"integer", ""},
+	"float":    {"number", ""},
+	"number":   {"number", ""},
+	"double":   {"number", ""},
+	"path":     {"string", "path"},
+	"file":     {"string", "path"},
+	"dir":      {"string", "path"},
+	"url":      {"string", "uri"},
+	"uri":      {"string", "uri"},
+	"duration": {"string", "duration"},
+}
+
+// 从 toks[*i] 开始解析类型；没有写类型时 flag 是 bool，arg 是 string
+func parseType(s *Statement, i *int, isArg bool) (map[string]any, error) {
+	toks := s.Tokens
+	if *i+1 < len(toks) && toks[*i].Text == "[" && toks[*i+1].Text == "]" {
+		*i += 2
+		items, err := parseType(s, i, isArg)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	}
+	if *i >= len(toks) || toks[*i].Kind != Ident || strings.EqualFold(toks[*i].Text, "required") || strings.EqualFold(toks[*i].Text, "optional") {
+		if isArg {
+			return map[string]any{"type": "string"}, nil
+		}
+		return map[string]any{"type": "boolean"}, nil
+	}
+
+	name := strings.ToLower(toks[*i].Text)
+	*i++
+	if name == "enum" {
+		return parseEnum(s, i)
+	}
+	t, ok := typeNames[name]
+	if !ok {
+		return nil, tokenError(s, *i-1, "unknown type %q", toks[*i-1].Text)
+	}
+	schema := map[string]any{"type": t[0]}
+	if t[1] != "" {
+		schema["format"] = t[1]
+	}
+	return schema, nil
+}
+
+// enum(a, b, "c d") 的可选值，全是字符串时类型为 string
+func parseEnum(s *Statement, i *int) (map[string]any, error) {
+	toks := s.Tokens
+	if *i >= len(toks) || toks[*i].Text != "(" {
+		return nil, tokenError(s, *i-1, "expected ( after enum")
+	}
+	*i++
+	var values []any
+	allStrings := true
+	for {
+		if *i >= len(toks) {
+			return nil, tokenError(s, len(toks)-1, "unclosed enum")
+		}
+		t := toks[*i]
+		*i++
+		switch {
+		case t.Kind == Punct && t.Text == ")":
+			if len(values) == 0 {
+				return nil, tokenError(s, *i-1, "enum needs at least one value")
+			}
+			schema := map[string]any{"enum": values}
+			if allStrings {
+				schema["type"] = "string"
+			}
+			return schema, nil
+		case t.Kind == Punct && t.Text == ",":
+		case t.Kind == Number:
+			n, err := strconv.ParseFloat(t.Text, 64)
+			if err != nil {
+				return nil, tokenError(s, *i-1, "invalid number %q", t.Text)
+			}
+			values = append(values, n)
+			allStrings = false
+		case t.Kind == Ident || t.Kind == String:
+			values = append(values, t.Text)
+		default:
+			return nil, tokenError(s, *i-1, "unexpected %q in enum", t.Text)
+		}
+	}
+}
+
+// 解析 = 之后的默认值，按类型转换
+func parseDefault(s *Statement, i *int, schema map[string]any) (any, error) {
+	toks := s.Tokens
+	if *i >= len(toks) {
+		return nil, tokenError(s, *i-1, "expected a default value after =")
+	}
+	neg := ""
+	if toks[*i].Kind == Punct && toks[*i].Text == "-" {
+		neg = "-"
+		*i++
+		if *i >= len(toks) || toks[*i].Kind != Number {
+			return nil, tokenError(s, *i-1, "expected a number after -")
+		}
+	}
+	t := toks[*i]
+	*i++
+	text := neg + t.Text
+	bad := func() error {
+		return tokenError(s, *i-1, "default %q does not match type %v", text, schema["type"])
+	}
+
+	switch schema["type"] {
+	case "boolean":
+		b, err := strconv.ParseBool(text)
+		if err != nil || t.Kind == String {
+			return nil, bad()
+		}
+		return b, nil
+	case "integer":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil || t.Kind != Number {
+			return nil, bad()
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil || t.Kind != Number {
+			return nil, bad()
+		}
+		return n, nil
+	case "array":
+		return nil, tokenError(s, *i-1, "defaults are not supported for repeated values")
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		for _, v := range enum {
+			if fmt.Sprint(v) == text {
+				return v, nil
+			}
+		}
+		return nil, tokenError(s, *i-1, "default %q is not one of the enum values", text)
+	}
+	return text, nil
+}
+
+// 指向语句中第 i 个词法单元的错误
+func tokenError(s *Statement, i int, format string, args ...any) error {
+	line, col := s.Line, 1
+	if i >= 0 && i < len(s.Tokens) {
+		line, col = s.Tokens[i].Line, s.Tokens[i].Col
+	}
+	return &SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,110 @@
+package hl
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Meta 是文件头部声明的元数据，没有声明的字段为空
+type Meta struct {
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Target 是 schema 针对的 shell 或平台
+	Target string `json:"target,omitempty"`
+}
+
+// IsZero 报告是否没有任何元数据
+func (m Meta) IsZero() bool {
+	return m == Meta{}
+}
+
+// 元数据的键及其别名
+var metaKeys = map[string]string{
+	"version":     "version",
+	"description": "description",
+	"desc":        "description",
+	"target":      "target",
+	"shell":       "target",
+}
+
+// ParseMeta 宽松地读取文件头部声明的元数据，不要求文件整体能够解析。头部是第一行其他代码之前的空行、
+// 注释和元数据语句，支持 "// version: 1.2" 形式的注释和 "version 1.2"、"version: 1.2"、"version = 1.2"
+// 形式的语句，值可以带引号；同一个键出现多次时使用第一次的值，没有可识别的头部时返回零值
+func ParseMeta(src []byte) Meta {
+	var m Meta
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if comment, ok := strings.CutPrefix(line, "//"); ok {
+			// 注释中只认 key: value，避免把普通的说明文字当成元数据
+			if key, value, ok := strings.Cut(comment, ":"); ok {
+				m.set(key, value)
+			}
+			continue
+		}
+		key, value, ok := metaStatement(line)
+		if !ok {
+			break
+		}
+		m.set(key, value)
+	}
+	return m
+}
+
+// 拆分 key value、key: value 或 key = value 形式的语句，键不是元数据时返回 false
+func metaStatement(line string) (key, value string, ok bool) {
+	line = strings.TrimSuffix(line, ";")
+	i := strings.IndexFunc(line, func(r rune) bool { return !isIdentPart(r) })
+	if i <= 0 {
+		return "", "", false
+	}
+	key, rest := line[:i], strings.TrimSpace(line[i:])
+	if _, known := metaKeys[strings.ToLower(key)]; !known {
+		return "", "", false
+	}
+	if r, found := strings.CutPrefix(rest, ":"); found {
+		rest = r
+	} else if r, found := strings.CutPrefix(rest, "="); found {
+		rest = r
+	} else if len(rest) == len(line[i:]) {
+		// 键后面必须有空白或分隔符
+		return "", "", false
+	}
+	// 带语句块的是普通语句，例如 target { ... }
+	if strings.ContainsAny(rest, "{}") {
+		return "", "", false
+	}
+	return key, rest, true
+}
+
+func (m *Meta) set(key, value string) {
+	value = unquote(strings.TrimSpace(value))
+	if value == "" {
+		return
+	}
+	var field *string
+	switch metaKeys[strings.ToLower(strings.TrimSpace(key))] {
+	case "version":
+		field = &m.Version
+	case "description":
+		field = &m.Description
+	case "target":
+		field = &m.Target
+	default:
+		return
+	}
+	if *field == "" {
+		*field = value
+	}
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'' || s[0] == '`') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,333 @@
+// Package hl 是 .hl schema 文件的宽松解析器。
+//
+// 解析器只识别块结构：语句由换行或分号结束，语句后紧跟的 { ... } 是它的子语句块。
+// 它检查字符串、注释和括号是否闭合，不理解具体的关键字。
+package hl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind 是词法单元的种类
+type TokenKind int
+
+const (
+	Ident TokenKind = iota
+	String
+	Number
+	Punct
+)
+
+// Token 是一个词法单元
+type Token struct {
+	Kind TokenKind
+	Text string
+	Line int
+	Col  int
+}
+
+// Statement 是一条语句及其可选的子语句块
+type Statement struct {
+	Line   int
+	Tokens []Token
+	// Body 为 nil 表示语句没有 { } 块
+	Body []*Statement
+}
+
+// File 是解析后的 .hl 文件
+type File struct {
+	// Comments 是文件开头连续的注释行，去掉了注释符号
+	Comments   []string
+	Statements []*Statement
+}
+
+// SyntaxError 描述解析失败的位置
+type SyntaxError struct {
+	Line int
+	Col  int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// Parse 解析 r 中的 .hl 内容
+func Parse(r io.Reader) (*File, error) {
+	src, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: string(src), line: 1, col: 1}
+	if !utf8.Valid(src) {
+		return nil, p.errorf("file is not valid UTF-8")
+	}
+	return p.parse()
+}
+
+// ParseString 解析字符串形式的 .hl 内容
+func ParseString(s string) (*File, error) {
+	return Parse(strings.NewReader(s))
+}
+
+type parser struct {
+	src  string
+	pos  int
+	line int
+	col  int
+
+	file *File
+	// 头部注释只收集第一条语句之前的内容
+	seenCode bool
+}
+
+type frame struct {
+	open  rune
+	line  int
+	col   int
+	stmts *[]*Statement
+	cur   *Statement
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: p.line, Col: p.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) peek() rune {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return r
+}
+
+func (p *parser) next() rune {
+	r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.pos += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+	return r
+}
+
+func (p *parser) parse() (*File, error) {
+	p.file = &File{}
+	root := &frame{stmts: &p.file.Statements}
+	stack := []*frame{root}
+	// 圆括号和方括号不会开启新的语句块，只要求成对出现
+	var groups []frame
+
+	top := func() *frame { return stack[len(stack)-1] }
+	endStmt := func() {
+		f := top()
+		if f.cur != nil {
+			*f.stmts = append(*f.stmts, f.cur)
+			f.cur = nil
+		}
+	}
+	addToken := func(t Token) {
+		p.seenCode = true
+		f := top()
+		if f.cur == nil {
+			f.cur = &Statement{Line: t.Line}
+		}
+		f.cur.Tokens = append(f.cur.Tokens, t)
+	}
+
+	for p.pos < len(p.src) {
+		line, col := p.line, p.col
+		r := p.peek()
+
+		switch {
+		case r == '\n' || r == ';':
+			p.next()
+			if len(groups) == 0 {
+				endStmt()
+			}
+		case unicode.IsSpace(r):
+			p.next()
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			text := p.lineComment()
+			if !p.seenCode {
+				p.file.Comments = append(p.file.Comments, strings.TrimSpace(strings.TrimPrefix(text, "//")))
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			if err := p.blockComment(); err != nil {
+				return nil, err
+			}
+		case r == '"' || r == '\'' || r == '`':
+			text, err := p.stringLit(r)
+			if err != nil {
+				return nil, err
+			}
+			addToken(Token{Kind: String, Text: text, Line: line, Col: col})
+		case r == '{':
+			p.next()
+			// 括号内的花括号只是字面量的一部分
+			if len(groups) > 0 {
+				groups = append(groups, frame{open: r, line: line, col: col})
+				addToken(Token{Kind: Punct, Text: string(r), Line: line, Col: col})
+				continue
+			}
+			f := top()
+			if f.cur == nil {
+				f.cur = &Statement{Line: line}
+			}
+			stmt := f.cur
+			stmt.Body = []*Statement{}
+			endStmt()
+			stack = append(stack, &frame{open: '{', line: line, col: col, stmts: &stmt.Body})
+			p.seenCode = true
+		case r == '}':
+			p.next()
+			if len(groups) > 0 {
+				g := groups[len(groups)-1]
+				if g.open != '{' {
+					return nil, &SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf("unexpected '}', expected closing for '%c' opened at %d:%d", g.open, g.line, g.col)}
+				}
+				groups = groups[:len(groups)-1]
+				addToken(Token{Kind: Punct, Text: string(r), Line: line, Col: col})
+				continue
+			}
+			if len(stack) == 1 {
+				return nil, &SyntaxError{Line: line, Col: col, Msg: "unexpected '}'"}
+			}
+			endStmt()
+			stack = stack[:len(stack)-1]
+		case r == '(' || r == '[':
+			p.next()
+			groups = append(groups, frame{open: r, line: line, col: col})
+			addToken(Token{Kind: Punct, Text: string(r), Line: line, Col: col})
+		case r == ')' || r == ']':
+			p.next()
+			want := map[rune]rune{')': '(', ']': '['}[r]
+			if len(groups) == 0 {
+				return nil, &SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf("unexpected '%c'", r)}
+			}
+			if g := groups[len(groups)-1]; g.open != want {
+				return nil, &SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf("unexpected '%c', expected closing for '%c' opened at %d:%d", r, g.open, g.line, g.col)}
+			}
+			groups = groups[:len(groups)-1]
+			addToken(Token{Kind: Punct, Text: string(r), Line: line, Col: col})
+		case isIdentStart(r):
+			addToken(Token{Kind: Ident, Text: p.take(isIdentPart), Line: line, Col: col})
+		case unicode.IsDigit(r):
+			addToken(Token{Kind: Number, Text: p.take(isNumberPart), Line: line, Col: col})
+		default:
+			p.next()
+			addToken(Token{Kind: Punct, Text: string(r), Line: line, Col: col})
+		}
+	}
+
+	if len(groups) > 0 {
+		g := groups[len(groups)-1]
+		return nil, &SyntaxError{Line: g.line, Col: g.col, Msg: fmt.Sprintf("unclosed '%c'", g.open)}
+	}
+	if len(stack) > 1 {
+		f := top()
+		return nil, &SyntaxError{Line: f.line, Col: f.col, Msg: "unclosed '{'"}
+	}
+	endStmt()
+	return p.file, nil
+}
+
+func (p *parser) take(ok func(rune) bool) string {
+	start := p.pos
+	for p.pos < len(p.src) && ok(p.peek()) {
+		p.next()
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) lineComment() string {
+	start := p.pos
+	for p.pos < len(p.src) && p.peek() != '\n' {
+		p.next()
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) blockComment() error {
+	line, col := p.line, p.col
+	p.next()
+	p.next()
+	for p.pos < len(p.src) {
+		if strings.HasPrefix(p.src[p.pos:], "*/") {
+			p.next()
+			p.next()
+			return nil
+		}
+		p.next()
+	}
+	return &SyntaxError{Line: line, Col: col, Msg: "unterminated block comment"}
+}
+
+// 读取字符串字面量，返回去掉引号后的内容；反引号字符串可以跨行且不处理转义
+func (p *parser) stringLit(quote rune) (string, error) {
+	line, col := p.line, p.col
+	p.next()
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		r := p.next()
+		switch {
+		case r == quote:
+			return b.String(), nil
+		case r == '\n' && quote != '`':
+			return "", &SyntaxError{Line: line, Col: col, Msg: "unterminated string literal"}
+		case r == '\\' && quote != '`' && p.pos < len(p.src):
+			b.WriteRune(unescape(p.next()))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", &SyntaxError{Line: line, Col: col, Msg: "unterminated string literal"}
+}
+
+func unescape(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	}
+	return r
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || r == '@' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || r == '-' || unicode.IsDigit(r)
+}
+
+func isNumberPart(r rune) bool {
+	return unicode.IsDigit(r) || r == '.' || r == '_' || unicode.IsLetter(r)
+}
+
+// 声明命令的关键字
+var commandKeywords = map[string]bool{"cmd": true, "command": true}
+
+// Commands 返回文件顶层声明的命令名：以 cmd 或 command 开头的语句中紧跟的标识符或字符串
+func (f *File) Commands() []string {
+	var names []string
+	for _, s := range f.Statements {
+		if len(s.Tokens) < 2 || s.Tokens[0].Kind != Ident || !commandKeywords[s.Tokens[0].Text] {
+			continue
+		}
+		if name := s.Tokens[1]; name.Kind == Ident || name.Kind == String {
+			names = append(names, name.Text)
+		}
+	}
+	return names
+}
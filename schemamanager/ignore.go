@@ -0,0 +1,134 @@
+package schemamanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName 是缓存根目录下的忽略文件，语法同 .gitignore
+const IgnoreFileName = ".hlignore"
+
+// 忽略文件中的一条规则
+type ignoreRule struct {
+	re *regexp.Regexp
+	// negate 对应 ! 开头的规则，重新包含之前被忽略的路径
+	negate bool
+	// dirOnly 对应 / 结尾的规则，只匹配目录
+	dirOnly bool
+	// anchored 的规则匹配完整相对路径，否则匹配任意层级的名称
+	anchored bool
+}
+
+type ignoreRules []ignoreRule
+
+// 读取并解析缓存根目录的 .hlignore，每个 Manager 只解析一次；NoIgnore 或文件不存在时没有规则
+func (m *Manager) ignoreRules() (ignoreRules, error) {
+	m.ignoreOnce.Do(func() {
+		if m.NoIgnore {
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(m.CacheDir, IgnoreFileName))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				m.ignoreErr = fmt.Errorf("reading %s: %w", IgnoreFileName, err)
+			}
+			return
+		}
+		m.ignore, m.ignoreErr = parseIgnore(data)
+		// 读到一半失败时使用之前的规则
+		var partial *partialReadError
+		if errors.As(m.ignoreErr, &partial) {
+			m.warnf("Warning: %s: %v; the remaining rules are not applied\n", IgnoreFileName, m.ignoreErr)
+			m.ignoreErr = nil
+		}
+		m.debugf("loaded %d rules from %s\n", len(m.ignore), IgnoreFileName)
+	})
+	return m.ignore, m.ignoreErr
+}
+
+// 解析 gitignore 风格的规则：支持 # 注释、! 取反、/ 结尾只匹配目录、含 / 的规则相对根目录匹配，以及 * ? ** 和字符类
+func parseIgnore(data []byte) (ignoreRules, error) {
+	var rules ignoreRules
+	scanner := lineScanner(bytes.NewReader(data))
+	n := 1
+	for ; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// 开头或中间的 / 表示相对根目录
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid pattern %q: %w", IgnoreFileName, n, scanner.Text(), err)
+		}
+		rule.re = regexp.MustCompile(globRegexp(line))
+		rules = append(rules, rule)
+	}
+	return rules, scanErr(scanner, n)
+}
+
+// 报告以 / 分隔的相对路径 relPath 本身是否被忽略，最后一条匹配的规则生效
+func (r ignoreRules) match(relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		subject := relPath
+		if !rule.anchored {
+			subject = path.Base(relPath)
+		}
+		if rule.re.MatchString(subject) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// 报告文件 relPath 是否被忽略；和 git 一样，所在目录被忽略时文件无法被重新包含
+func (r ignoreRules) ignored(relPath string) bool {
+	if len(r) == 0 {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+	return r.dirIgnored(path.Dir(relPath)) || r.match(relPath, false)
+}
+
+// 报告目录 dir 或它的任一上级目录是否被忽略
+func (r ignoreRules) dirIgnored(dir string) bool {
+	if len(r) == 0 || dir == "." || dir == "" {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(dir), "/")
+	for i := 1; i <= len(parts); i++ {
+		if r.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return false
+}
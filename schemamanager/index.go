@@ -0,0 +1,157 @@
+package schemamanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// 磁盘索引，记录某个 HEAD 下缓存中所有 .hl 文件，HEAD 变化后失效
+type index struct {
+	CacheDir string `json:"cacheDir"`
+	Head     string `json:"head"`
+	// Hidden 记录索引是否包含隐藏目录中的文件
+	Hidden bool `json:"hidden"`
+	// Symlinks 记录建立索引时是否跟随了符号链接
+	Symlinks bool `json:"symlinks"`
+	// Exts 是建立索引时使用的扩展名
+	Exts  []string `json:"exts"`
+	Files []File   `json:"files"`
+}
+
+// DefaultIndexPath 返回缓存目录旁的索引文件路径，默认缓存对应 ~/.opencmd/index.json
+func DefaultIndexPath(cacheDir string) string {
+	return filepath.Join(filepath.Dir(cacheDir), "index.json")
+}
+
+// RebuildIndex 完整遍历缓存并重写索引文件，返回索引中的文件数
+func (m *Manager) RebuildIndex() (int, error) {
+	if !m.Exists() {
+		return 0, ErrNotInitialized
+	}
+	if m.IndexPath == "" {
+		return 0, fmt.Errorf("no index path configured")
+	}
+	if err := m.requireWorktree("the file index"); err != nil {
+		return 0, err
+	}
+	idx, err := m.buildIndex()
+	if err != nil {
+		return 0, err
+	}
+	return len(idx.Files), nil
+}
+
+// 返回当前 HEAD 的索引，索引缺失或过期时重建；缓存不是可读的 git 仓库时返回 nil
+func (m *Manager) currentIndex() (*index, error) {
+	if _, err := m.walkRoot(); err != nil {
+		return nil, err
+	}
+	if err := checkExclude(m.Exclude); err != nil {
+		return nil, err
+	}
+	if _, err := m.ignoreRules(); err != nil {
+		return nil, err
+	}
+	head, err := m.headHash()
+	if err != nil {
+		return nil, nil
+	}
+
+	if data, err := os.ReadFile(m.IndexPath); err == nil {
+		var idx index
+		if json.Unmarshal(data, &idx) == nil && idx.CacheDir == m.CacheDir && idx.Head == head && idx.Hidden == m.IncludeHidden && idx.Symlinks == m.FollowSymlinks && slices.Equal(idx.Exts, m.extensions()) {
+			m.debugf("using index %s (HEAD %s)\n", m.IndexPath, head[:8])
+			return &idx, nil
+		}
+	}
+	m.debugf("rebuilding index %s\n", m.IndexPath)
+	return m.buildIndex()
+}
+
+// 不带 Dir、排除模式、.hlignore 和深度限制遍历整个缓存并写入索引，它们在读取时再应用
+func (m *Manager) buildIndex() (*index, error) {
+	head, err := m.headHash()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := m.walk(m.CacheDir, nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	files, err := m.stat(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &index{CacheDir: m.CacheDir, Head: head, Hidden: m.IncludeHidden, Symlinks: m.FollowSymlinks, Exts: m.extensions(), Files: files}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return nil, err
+	}
+	// 索引只是加速手段，写入失败时给出警告继续使用遍历结果
+	if err := os.WriteFile(m.IndexPath, data, 0644); err != nil {
+		m.warnf("Warning: writing index: %v\n", err)
+	}
+	return idx, nil
+}
+
+// 按 Dir、排除模式、.hlignore 和最大深度过滤索引中的文件，路径上任一级目录被排除时文件也被排除
+func (m *Manager) filterIndexed(files []File) []File {
+	rules, _ := m.ignoreRules()
+	maxDepth := m.maxDepth()
+	if len(m.Exclude) == 0 && m.Dir == "" && len(rules) == 0 && maxDepth < 0 && m.MinSize == nil && m.MaxSize == nil {
+		return files
+	}
+	prefix := ""
+	if m.Dir != "" {
+		prefix = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(m.Dir)), "/") + "/"
+		if prefix == "./" {
+			prefix = ""
+		}
+	}
+	var kept []File
+	for _, f := range files {
+		if !strings.HasPrefix(filepath.ToSlash(f.Path), prefix) {
+			continue
+		}
+		if rules.ignored(f.Path) {
+			continue
+		}
+		if maxDepth >= 0 && depth(filepath.Dir(f.Path)) > maxDepth {
+			continue
+		}
+		if !m.sizeOK(f.Size) {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(f.Path), "/")
+		skip := false
+		for i := range parts {
+			path := filepath.Join(m.CacheDir, filepath.FromSlash(strings.Join(parts[:i+1], "/")))
+			if excluded(m.CacheDir, m.Exclude, path, parts[i]) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func (m *Manager) headHash() (string, error) {
+	repo, err := m.open()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("getting HEAD: %w", err)
+	}
+	return head.Hash().String(), nil
+}
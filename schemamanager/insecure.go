@@ -0,0 +1,15 @@
+package schemamanager
+
+import "strings"
+
+// 是否跳过 HTTPS 证书校验；每个 Manager 第一次跳过时给出警告
+func (m *Manager) insecureTLS(repoURL string) bool {
+	if !m.InsecureSkipTLS {
+		return false
+	}
+	if !m.warnedInsecure && strings.HasPrefix(strings.ToLower(repoURL), "https://") {
+		m.warnedInsecure = true
+		m.warnf("WARNING: TLS certificate verification is disabled for %s; the server's identity is not checked.\n", redactURL(repoURL))
+	}
+	return true
+}
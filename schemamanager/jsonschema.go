@@ -0,0 +1,92 @@
+package schemamanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"schema-manager/schemamanager/hl"
+)
+
+// ExportSkip 是转换为 JSON Schema 时被跳过的文件和原因
+type ExportSkip struct {
+	Path string
+	Err  error
+}
+
+// JSONSchemas 把 paths 中的 .hl 文件转换为 JSON Schema 文档，结果和 paths 一一对应，跳过的文件为 nil。
+// 读取失败说明缓存有问题，直接返回错误；只有无法转换的文件被跳过。设置了 Ref 时读取该版本中的内容
+func (m *Manager) JSONSchemas(paths []string) ([][]byte, []ExportSkip, error) {
+	if !m.Exists() {
+		return nil, nil, ErrNotInitialized
+	}
+	sources, err := parallel(m.jobs(), len(paths), func(i int) ([]byte, error) {
+		return m.ReadFile(paths[i])
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	docs := make([][]byte, len(paths))
+	var skipped []ExportSkip
+	for i, p := range paths {
+		if docs[i], err = hl.ToJSONSchema(sources[i]); err != nil {
+			skipped = append(skipped, ExportSkip{Path: p, Err: err})
+		}
+	}
+	return docs, skipped, nil
+}
+
+// JSONSchemaName 是 relPath 导出为 JSON Schema 时的文件名：去掉配置的扩展名后加上 .schema.json
+func (m *Manager) JSONSchemaName(relPath string) string {
+	return strings.TrimSuffix(relPath, m.SchemaExt(relPath)) + ".schema.json"
+}
+
+// ExportJSONSchema 把 paths 转换为 JSON Schema 写入 opts.Dest，返回写入的文件数和跳过的文件。
+// 无法转换的文件不影响其他文件；写入前先检查冲突，和 Export 一样不会只写一部分
+func (m *Manager) ExportJSONSchema(paths []string, opts ExportOptions) (int, []ExportSkip, error) {
+	docs, skipped, err := m.JSONSchemas(paths)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	type writeJob struct {
+		path, dst string
+		data      []byte
+	}
+	var jobs []writeJob
+	targets := make(map[string]string, len(paths))
+	for i, p := range paths {
+		if docs[i] == nil {
+			continue
+		}
+		rel := m.JSONSchemaName(p)
+		if opts.Flatten {
+			rel = filepath.Base(rel)
+		}
+		dst := filepath.Join(opts.Dest, rel)
+		if other, ok := targets[dst]; ok {
+			return 0, skipped, fmt.Errorf("%s and %s would both be exported as %s", other, p, dst)
+		}
+		if !opts.Force {
+			if _, err := os.Stat(dst); err == nil {
+				return 0, skipped, fmt.Errorf("%s already exists; use --force to overwrite", dst)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return 0, skipped, err
+			}
+		}
+		targets[dst] = p
+		jobs = append(jobs, writeJob{path: p, dst: dst, data: append(docs[i], '\n')})
+	}
+
+	for i, job := range jobs {
+		if err := os.MkdirAll(filepath.Dir(job.dst), 0755); err != nil {
+			return i, skipped, err
+		}
+		if err := os.WriteFile(job.dst, job.data, 0644); err != nil {
+			return i, skipped, fmt.Errorf("exporting %s: %w", job.path, err)
+		}
+	}
+	return len(jobs), skipped, nil
+}
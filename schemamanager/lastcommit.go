@@ -0,0 +1,194 @@
+package schemamanager
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+)
+
+// LastCommit 是最后一次修改某个文件的提交
+type LastCommit struct {
+	Hash    string    `json:"hash"`
+	When    time.Time `json:"when"`
+	Subject string    `json:"subject"`
+}
+
+// DefaultCommitCachePath 返回缓存目录旁的最后修改提交缓存文件路径，默认缓存对应 ~/.opencmd/commits.json
+func DefaultCommitCachePath(cacheDir string) string {
+	return filepath.Join(filepath.Dir(cacheDir), "commits.json")
+}
+
+// 缓存的键由路径和 blob 哈希组成：文件内容不变时最后修改它的提交也不变；
+// 同样的内容可能出现在多个路径，只用 blob 哈希会把它们混在一起
+func commitCacheKey(path string, blob plumbing.Hash) string {
+	return blob.String() + " " + path
+}
+
+// LastCommits 查找最后修改每个文件的提交，返回以路径为键的结果；不在提交树中的文件（例如未提交的新文件）没有结果。
+// 比较的是 HEAD，设置了 Ref 时是该版本。需要遍历历史，代价较高：文件分成 Jobs 组并发查找，
+// 结果按路径和 blob 哈希记录在 CommitCachePath 中，文件内容没变时直接使用记录
+func (m *Manager) LastCommits(files []File) (map[string]LastCommit, error) {
+	repo, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := m.treeCommit(repo)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := m.loadCommitCache()
+	result := map[string]LastCommit{}
+	blobs := map[string]plumbing.Hash{}
+	var pending []string
+	for _, f := range files {
+		entry, err := tree.FindEntry(f.Path)
+		if err != nil {
+			continue
+		}
+		blobs[f.Path] = entry.Hash
+		if c, ok := cache[commitCacheKey(f.Path, entry.Hash)]; ok {
+			result[f.Path] = c
+			continue
+		}
+		pending = append(pending, f.Path)
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	// go-git 的仓库对象不能安全地在协程间共享，每组单独打开仓库并各自遍历一次历史
+	groups := min(m.jobs(), len(pending))
+	found, err := parallel(groups, groups, func(i int) (map[string]LastCommit, error) {
+		var paths []string
+		for j := i; j < len(pending); j += groups {
+			paths = append(paths, pending[j])
+		}
+		repo, err := m.open()
+		if err != nil {
+			return nil, err
+		}
+		return lastTouched(repo, commit.Hash, paths)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range found {
+		for path, c := range group {
+			result[path] = c
+		}
+	}
+	// 只保留这次用到的记录，删掉已经不存在的文件内容，避免记录越积越多
+	kept := make(map[string]LastCommit, len(result))
+	for path, c := range result {
+		kept[commitCacheKey(path, blobs[path])] = c
+	}
+	m.saveCommitCache(kept)
+	return result, nil
+}
+
+// 从 from 按提交时间从新到旧遍历历史，找出每个路径最后一次被修改的提交：
+// 该提交中的文件和所有父提交都不同（合并提交和某个父提交相同时不算）。浅克隆缺失的父提交当作不存在
+func lastTouched(repo *git.Repository, from plumbing.Hash, paths []string) (map[string]LastCommit, error) {
+	want := map[string]bool{}
+	for _, p := range paths {
+		want[p] = true
+	}
+	found := map[string]LastCommit{}
+
+	iter, err := repo.Log(&git.LogOptions{From: from, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	err = iter.ForEach(func(c *object.Commit) error {
+		tree, err := c.Tree()
+		if err != nil {
+			return err
+		}
+		var parents []*object.Tree
+		for _, h := range c.ParentHashes {
+			parent, err := repo.CommitObject(h)
+			if err != nil {
+				continue
+			}
+			if t, err := parent.Tree(); err == nil {
+				parents = append(parents, t)
+			}
+		}
+
+		for path := range want {
+			blob := blobAt(tree, path)
+			touched := true
+			for _, t := range parents {
+				if blobAt(t, path) == blob {
+					touched = false
+					break
+				}
+			}
+			if touched {
+				subject, _, _ := strings.Cut(c.Message, "\n")
+				found[path] = LastCommit{Hash: c.Hash.String(), When: c.Committer.When, Subject: subject}
+				delete(want, path)
+			}
+		}
+		if len(want) == 0 {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	// 浅克隆的历史在缺失的提交处结束
+	if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, err
+	}
+	return found, nil
+}
+
+// 树中 path 对应的 blob 哈希，不存在时为零值
+func blobAt(tree *object.Tree, path string) plumbing.Hash {
+	entry, err := tree.FindEntry(path)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return entry.Hash
+}
+
+func (m *Manager) loadCommitCache() map[string]LastCommit {
+	cache := map[string]LastCommit{}
+	if m.CommitCachePath == "" {
+		return cache
+	}
+	if data, err := os.ReadFile(m.CommitCachePath); err == nil {
+		// 文件损坏时当作没有记录，之后整个重写
+		if json.Unmarshal(data, &cache) != nil {
+			cache = map[string]LastCommit{}
+		}
+	}
+	return cache
+}
+
+// 写入失败只给出警告
+func (m *Manager) saveCommitCache(cache map[string]LastCommit) {
+	if m.CommitCachePath == "" {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err == nil {
+		err = os.WriteFile(m.CommitCachePath, data, 0644)
+	}
+	if err != nil {
+		m.warnf("Warning: saving commit date cache: %v\n", err)
+	}
+}
@@ -0,0 +1,60 @@
+package schemamanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked 表示在超时前没有拿到缓存锁，另一个进程正在使用缓存
+var ErrLocked = errors.New("cache is locked by another schema-manager process")
+
+// 重试获取锁的间隔
+const lockPollInterval = 100 * time.Millisecond
+
+// DefaultLockPath 返回缓存目录旁的锁文件路径，默认缓存对应 ~/.opencmd/lock
+func DefaultLockPath(cacheDir string) string {
+	return filepath.Join(filepath.Dir(cacheDir), "lock")
+}
+
+// Lock 获取 LockPath 上的咨询锁：exclusive 为 true 时是写操作使用的排他锁，否则是读操作使用的共享锁。
+// 锁被占用时每隔一段时间重试，timeout 内仍未拿到时返回 ErrLocked；timeout 为 0 时只尝试一次。
+// 返回的函数释放锁；锁随文件描述符存在，进程因信号退出时由操作系统释放。LockPath 为空时不加锁
+func (m *Manager) Lock(exclusive bool, timeout time.Duration) (func() error, error) {
+	if m.LockPath == "" {
+		return func() error { return nil }, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(m.LockPath), 0755); err != nil {
+		return nil, fmt.Errorf("creating lock directory: %w", err)
+	}
+	f, err := os.OpenFile(m.LockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := tryLock(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("locking %s: %w", m.LockPath, err)
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w (lock file %s); try again later or raise --lock-timeout", ErrLocked, m.LockPath)
+		}
+		m.debugf("waiting for lock %s\n", m.LockPath)
+		time.Sleep(lockPollInterval)
+	}
+
+	return func() error {
+		// 关闭文件描述符同时释放锁
+		unlock(f)
+		return f.Close()
+	}, nil
+}
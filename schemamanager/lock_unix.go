@@ -0,0 +1,26 @@
+//go:build unix
+
+package schemamanager
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// 非阻塞地尝试 flock，锁被占用时返回 false
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(f *os.File) {
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,134 @@
+package schemamanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// ObjectStats 是缓存仓库对象库的概况
+type ObjectStats struct {
+	// LooseObjects 和 LooseSize 是未打包的对象数和占用的字节数
+	LooseObjects int   `json:"looseObjects"`
+	LooseSize    int64 `json:"looseSize"`
+	// Packs 和 PackSize 是包文件数和 objects/pack 目录占用的字节数，包括索引文件
+	Packs    int   `json:"packs"`
+	PackSize int64 `json:"packSize"`
+}
+
+// Size 是对象库占用的总字节数
+func (s ObjectStats) Size() int64 {
+	return s.LooseSize + s.PackSize
+}
+
+// MaintainOptions 控制 Maintain 的整理方式
+type MaintainOptions struct {
+	// Aggressive 为 true 时立即清理所有不可达的对象，并在系统装有 git 时改用 git gc --aggressive 重新计算差异
+	Aggressive bool
+}
+
+// MaintainResult 是整理前后的对象库概况
+type MaintainResult struct {
+	Before ObjectStats `json:"before"`
+	After  ObjectStats `json:"after"`
+	// Method 说明实际的整理方式：go-git，或者调用系统 git 时的完整命令
+	Method string `json:"method"`
+}
+
+// 默认只清理两周前的不可达对象，和 git gc 一致，避免删除其他进程刚写入的对象
+const pruneGrace = 14 * 24 * time.Hour
+
+// 缓存仓库的 git 目录，裸仓库就是缓存目录本身
+func (m *Manager) gitDir() string {
+	if m.IsBare() {
+		return m.CacheDir
+	}
+	return filepath.Join(m.CacheDir, ".git")
+}
+
+// ObjectStats 统计缓存仓库中的松散对象和包文件
+func (m *Manager) ObjectStats() (ObjectStats, error) {
+	var stats ObjectStats
+	if _, err := m.open(); err != nil {
+		return stats, err
+	}
+	objects := filepath.Join(m.gitDir(), "objects")
+	entries, err := os.ReadDir(objects)
+	if err != nil {
+		return stats, fmt.Errorf("reading object store: %w", err)
+	}
+	for _, e := range entries {
+		// 松散对象按哈希前两位分目录存放
+		isLoose := e.IsDir() && len(e.Name()) == 2
+		if !isLoose && e.Name() != "pack" {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(objects, e.Name()))
+		if err != nil {
+			return stats, fmt.Errorf("reading object store: %w", err)
+		}
+		for _, f := range files {
+			info, err := f.Info()
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if isLoose {
+				stats.LooseObjects++
+				stats.LooseSize += info.Size()
+				continue
+			}
+			if filepath.Ext(f.Name()) == ".pack" {
+				stats.Packs++
+			}
+			stats.PackSize += info.Size()
+		}
+	}
+	return stats, nil
+}
+
+// Maintain 清理不可达的松散对象并把对象重新打包成一个包文件。默认使用 go-git；
+// go-git 不会重新计算差异，Aggressive 时如果系统装有 git 则改用 git gc --aggressive --prune=now
+func (m *Manager) Maintain(ctx context.Context, opts MaintainOptions) (MaintainResult, error) {
+	var result MaintainResult
+	repo, err := m.open()
+	if err != nil {
+		return result, err
+	}
+	if result.Before, err = m.ObjectStats(); err != nil {
+		return result, err
+	}
+
+	if path, lookErr := exec.LookPath("git"); opts.Aggressive && lookErr == nil {
+		args := []string{"--git-dir", m.gitDir(), "gc", "--aggressive", "--prune=now", "--quiet"}
+		result.Method = "git gc --aggressive --prune=now"
+		m.debugf("running %s %v\n", path, args)
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stderr = m.Warnings
+		if err := cmd.Run(); err != nil {
+			return result, fmt.Errorf("running %s: %w", result.Method, err)
+		}
+	} else {
+		result.Method = "go-git"
+		cutoff := time.Now().Add(-pruneGrace)
+		if opts.Aggressive {
+			cutoff = time.Now()
+		}
+		err := repo.Prune(git.PruneOptions{OnlyObjectsOlderThan: cutoff, Handler: repo.DeleteObject})
+		if err != nil {
+			return result, fmt.Errorf("pruning unreachable objects: %w", err)
+		}
+		if err := repo.RepackObjects(&git.RepackConfig{}); err != nil {
+			return result, fmt.Errorf("repacking objects: %w", err)
+		}
+	}
+
+	if result.After, err = m.ObjectStats(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
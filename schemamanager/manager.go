@@ -0,0 +1,467 @@
+// Package schemamanager 管理从 opencommand/commands 仓库缓存到本地的命令 schema。
+package schemamanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// DefaultRepoURL 是默认的 schema 仓库地址
+const DefaultRepoURL = "https://github.com/opencommand/commands"
+
+// 缓存仓库 .git/config 中保存工具状态的节名
+const configSection = "schema-manager"
+
+// ErrNotInitialized 表示缓存目录还没有克隆仓库
+var ErrNotInitialized = errors.New("repository not found; run 'schema-manager init' first")
+
+// ErrCorruptCache 表示缓存目录存在但不是可用的仓库，例如克隆被中断
+var ErrCorruptCache = errors.New("cache directory exists but is not a valid repository; run 'schema-manager init --repair'")
+
+// CacheState 是缓存目录的状态
+type CacheState int
+
+const (
+	// CacheMissing 表示缓存目录不存在
+	CacheMissing CacheState = iota
+	// CacheValid 表示缓存目录是可以打开的仓库
+	CacheValid
+	// CacheBroken 表示缓存目录存在但无法作为仓库打开或没有 HEAD
+	CacheBroken
+)
+
+func (s CacheState) String() string {
+	switch s {
+	case CacheMissing:
+		return "missing"
+	case CacheValid:
+		return "valid"
+	default:
+		return "broken"
+	}
+}
+
+// Manager 操作位于 CacheDir 的本地缓存仓库
+type Manager struct {
+	// CacheDir 是缓存仓库所在目录
+	CacheDir string
+	// RepoURL 是克隆和比较使用的远程仓库地址
+	RepoURL string
+	// Branch 指定克隆的分支或标签，为空时使用远程默认分支
+	Branch string
+	// Bare 为 true 时 Clone 只克隆 git 对象、不检出工作区；已有缓存是否为裸仓库以 IsBare 为准
+	Bare bool
+	// NoCheckout 为 true 时 Clone 保留工作区目录但不检出文件，记录在缓存仓库的配置中；已有缓存以 IsNoCheckout 为准
+	NoCheckout bool
+	// SparseDirs 非空时 Clone 只把这些目录检出到工作区，其余文件只保存在 git 对象中；
+	// 目录记录在缓存仓库的配置中，之后的 Update、Pin 和 Unpin 同样只检出这些目录
+	SparseDirs []string
+	// Ref 非空时 List、Search、ReadFile、Checksum 和 Stats 从这个版本的提交树中读取文件，不读工作区；
+	// 树中没有修改时间，File.ModTime 是该提交的时间
+	Ref string
+	// Depth 大于 0 时进行浅克隆
+	Depth int
+	// Token 是访问私有 HTTP 仓库的令牌，不会出现在错误信息中
+	Token string
+	// Proxy 是访问 HTTP 仓库使用的代理地址，可以带用户名和密码；为空时按 HTTPS_PROXY 等环境变量选择
+	Proxy string
+	// InsecureSkipTLS 为 true 时访问 HTTPS 仓库不校验服务器证书，用于自签名证书的内部镜像；第一次使用时给出警告
+	InsecureSkipTLS bool
+	// Jobs 是遍历和匹配文件时的并发数，小于等于 0 时使用 CPU 核数
+	Jobs int
+	// Dir 是相对缓存目录的子目录，非空时只遍历该目录，返回的路径仍然相对缓存目录
+	Dir string
+	// Extensions 是要处理的文件扩展名，开头的 . 可以省略，为空时只处理 .hl 文件
+	Extensions []string
+	// FollowSymlinks 为 true 时进入指向缓存内目录的符号链接，返回的路径是链接所在的位置；
+	// 默认不跟随。按真实路径记录当前链路上经过的目录，指向其中任一目录或其上级的链接会成环，直接跳过
+	FollowSymlinks bool
+	// IncludeHidden 为 true 时遍历 .git 等以 . 开头的目录和文件，默认跳过
+	IncludeHidden bool
+	// Exclude 是遍历时排除的 glob 模式，任一模式匹配即排除；匹配到目录时整个目录被跳过。
+	// 不含 / 的模式匹配任意层级的文件或目录名，含 / 的模式匹配以 / 分隔的完整相对路径
+	Exclude []string
+	// MaxDepth 非 nil 时只收集缓存根目录下至多 *MaxDepth 层目录中的文件，0 表示只有根目录下的文件；
+	// 深度总是从缓存根目录算起，和 Dir 无关
+	MaxDepth *int
+	// MinSize 和 MaxSize 非 nil 时只收集大小（字节）在该范围内的文件，两端都包含
+	MinSize *int64
+	MaxSize *int64
+	// NoIgnore 为 true 时不读取缓存根目录的 .hlignore；默认按其中的规则排除文件
+	NoIgnore bool
+	// IndexPath 是磁盘索引文件路径，List 和按文件名 Search 在 HEAD 未变化时读取索引而不遍历目录；为空时不使用索引
+	IndexPath string
+	// RemoteCachePath 是记录上次查询到的远程引用的文件，为空时不使用；RemoteMaxAge 大于 0 时，
+	// Status 在记录未超过该时长时直接使用记录而不访问网络。无法访问远程时总会退回使用记录
+	RemoteCachePath string
+	RemoteMaxAge    time.Duration
+	// CommitCachePath 是 LastCommits 记录查找结果的文件，为空时每次都遍历历史
+	CommitCachePath string
+	// LockPath 是 Lock 使用的锁文件，多个进程通过它互斥地修改缓存；为空时 Lock 不加锁
+	LockPath string
+	// Retries 是网络操作遇到暂时故障时的重试次数，RetryDelay 是首次重试前的等待时间，之后每次翻倍
+	Retries    int
+	RetryDelay time.Duration
+	// DetectRateLimits 让网络操作记录经过 NewHTTPTransport 的限流响应，用于报告主机和等待时间
+	DetectRateLimits bool
+	// Warnings 接收跳过文件等非致命警告，为 nil 时丢弃
+	Warnings io.Writer
+	// Verbose 接收调试信息，例如遍历到的每个文件和使用的引用，为 nil 时丢弃
+	Verbose io.Writer
+	// Progress 接收 git 传输进度，为 nil 时不显示
+	Progress io.Writer
+
+	warnMu sync.Mutex
+	// 已经给出过跳过证书校验的警告
+	warnedInsecure bool
+
+	// 解析后的 .hlignore 规则
+	ignoreOnce sync.Once
+	ignore     ignoreRules
+	ignoreErr  error
+
+	// 网络操作中记录到的限流响应
+	rateLimits rateLimitLog
+}
+
+// New 返回使用默认仓库地址的 Manager
+func New(cacheDir string) *Manager {
+	return &Manager{CacheDir: cacheDir, RepoURL: DefaultRepoURL}
+}
+
+// Exists 报告缓存目录是否存在
+func (m *Manager) Exists() bool {
+	_, err := os.Stat(m.CacheDir)
+	return err == nil
+}
+
+// State 区分缓存目录不存在、可用和损坏三种状态
+func (m *Manager) State() CacheState {
+	if !m.Exists() {
+		return CacheMissing
+	}
+	repo, err := git.PlainOpen(m.CacheDir)
+	if err != nil {
+		return CacheBroken
+	}
+	if _, err := repo.Head(); err != nil {
+		return CacheBroken
+	}
+	return CacheValid
+}
+
+// Remove 删除整个缓存目录、索引文件、远程引用缓存和最后修改提交的缓存
+func (m *Manager) Remove() error {
+	for _, path := range []string{m.IndexPath, m.RemoteCachePath, m.CommitCachePath} {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.RemoveAll(m.CacheDir)
+}
+
+// DiskUsage 统计缓存目录中的文件数和总字节数
+func (m *Manager) DiskUsage() (files int, size int64, err error) {
+	err = filepath.Walk(m.CacheDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files++
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size, err
+}
+
+// Init 解析 Branch 并克隆仓库，缓存已存在时返回错误
+func (m *Manager) Init(ctx context.Context) error {
+	ref, err := m.ResolveRef(ctx)
+	if err != nil {
+		return err
+	}
+	if m.Exists() {
+		return fmt.Errorf("repository already exists at: %s", m.CacheDir)
+	}
+	return m.Clone(ctx, ref)
+}
+
+// ResolveRef 在远程查找 Branch 对应的分支或标签，分支优先；Branch 为空时返回空引用
+func (m *Manager) ResolveRef(ctx context.Context) (plumbing.ReferenceName, error) {
+	if m.Branch == "" {
+		return "", nil
+	}
+
+	refs, err := m.RemoteRefs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("resolving branch: %w", err)
+	}
+
+	branchRef := plumbing.NewBranchReferenceName(m.Branch)
+	tagRef := plumbing.NewTagReferenceName(m.Branch)
+	var tagFound bool
+	for _, ref := range refs {
+		switch ref.Name() {
+		case branchRef:
+			return branchRef, nil
+		case tagRef:
+			tagFound = true
+		}
+	}
+
+	if tagFound {
+		return tagRef, nil
+	}
+	return "", fmt.Errorf("resolving branch: %w", refNotFound(m.Branch, m.RepoURL, refs))
+}
+
+// RemoteRefs 列出 RepoURL 上的引用，不需要本地缓存，可以用来检查远程是否可达
+func (m *Manager) RemoteRefs(ctx context.Context) ([]*plumbing.Reference, error) {
+	if err := ValidateRepoURL(m.RepoURL); err != nil {
+		return nil, err
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{m.RepoURL},
+	})
+
+	auth, err := m.auth(m.RepoURL)
+	if err != nil {
+		return nil, fmt.Errorf("preparing credentials: %w", err)
+	}
+	proxy, err := m.proxy(m.RepoURL)
+	if err != nil {
+		return nil, err
+	}
+	insecure := m.insecureTLS(m.RepoURL)
+	var refs []*plumbing.Reference
+	err = m.retry(ctx, "listing remote refs", func(ctx context.Context) (err error) {
+		refs, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth, ProxyOptions: proxy, InsecureSkipTLS: insecure})
+		return err
+	})
+	if err != nil {
+		return nil, m.remoteErr(err)
+	}
+	return refs, nil
+}
+
+// Clone 把仓库克隆到 CacheDir；ref 非空时只克隆该引用并记录下来。
+// 克隆失败或 ctx 被取消时删除本次创建的目录，原本就存在的上级目录保持不变
+func (m *Manager) Clone(ctx context.Context, ref plumbing.ReferenceName) (err error) {
+	if err := ValidateRepoURL(m.RepoURL); err != nil {
+		return err
+	}
+	sparse, err := cleanSparseDirs(m.SparseDirs)
+	if err != nil {
+		return err
+	}
+	if len(sparse) > 0 && m.Bare {
+		return fmt.Errorf("sparse checkout paths cannot be combined with a bare clone")
+	}
+	if m.NoCheckout && (m.Bare || len(sparse) > 0) {
+		return fmt.Errorf("no-checkout cannot be combined with a bare clone or sparse checkout paths")
+	}
+	created := firstMissing(m.CacheDir)
+	if err := os.MkdirAll(m.CacheDir, 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	defer func() {
+		if err != nil && created != "" {
+			m.debugf("removing partial clone %s\n", created)
+			if rmErr := os.RemoveAll(created); rmErr != nil {
+				m.warnf("Warning: removing partial clone %s: %v\n", created, rmErr)
+			}
+		}
+	}()
+
+	auth, err := m.auth(m.RepoURL)
+	if err != nil {
+		return fmt.Errorf("preparing credentials: %w", err)
+	}
+	proxy, err := m.proxy(m.RepoURL)
+	if err != nil {
+		return err
+	}
+	insecure := m.insecureTLS(m.RepoURL)
+
+	options := &git.CloneOptions{
+		URL:             m.RepoURL,
+		Auth:            auth,
+		ProxyOptions:    proxy,
+		InsecureSkipTLS: insecure,
+		Progress:        m.Progress,
+		Bare:            m.Bare,
+		NoCheckout:      len(sparse) > 0 || m.NoCheckout, // 稀疏检出时克隆后只检出指定的目录
+	}
+
+	// 指定分支或标签时只克隆该引用
+	if ref != "" {
+		options.ReferenceName = ref
+		options.SingleBranch = true
+	}
+
+	// 浅克隆只保留最近的若干提交
+	if m.Depth > 0 {
+		options.Depth = m.Depth
+	}
+
+	m.debugf("cloning %s (ref %q, depth %d) into %s\n", m.RepoURL, ref, m.Depth, m.CacheDir)
+	var repo *git.Repository
+	err = m.retry(ctx, "cloning", func(ctx context.Context) (err error) {
+		// 失败的克隆会留下部分文件，重试前清空目录
+		if err := m.resetDir(); err != nil {
+			return err
+		}
+		repo, err = git.PlainCloneContext(ctx, m.CacheDir, options)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("cloning repository: %w", m.remoteErr(err))
+	}
+
+	if ref != "" {
+		if err := saveTrackedRef(repo, ref); err != nil {
+			return fmt.Errorf("saving tracked branch: %w", err)
+		}
+	}
+	if len(sparse) > 0 {
+		return m.checkoutSparse(repo, sparse)
+	}
+	if m.NoCheckout {
+		return saveNoCheckout(repo)
+	}
+	return nil
+}
+
+func (m *Manager) open() (*git.Repository, error) {
+	if !m.Exists() {
+		return nil, ErrNotInitialized
+	}
+	repo, err := git.PlainOpen(m.CacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+	}
+	return repo, nil
+}
+
+// 返回 path 及其上级中最靠上的不存在的目录，即 MkdirAll 会新建的部分；path 已存在时返回空串
+func firstMissing(path string) string {
+	missing := ""
+	for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			return missing
+		}
+		missing = p
+		if filepath.Dir(p) == p {
+			return missing
+		}
+	}
+}
+
+// 清空并重新创建缓存目录
+func (m *Manager) resetDir() error {
+	if err := os.RemoveAll(m.CacheDir); err != nil {
+		return err
+	}
+	return os.MkdirAll(m.CacheDir, 0755)
+}
+
+func (m *Manager) warnf(format string, args ...any) {
+	if m.Warnings != nil {
+		// 警告可能来自多个工作协程
+		m.warnMu.Lock()
+		defer m.warnMu.Unlock()
+		fmt.Fprintf(m.Warnings, format, args...)
+	}
+}
+
+func (m *Manager) debugf(format string, args ...any) {
+	if m.Verbose != nil {
+		m.warnMu.Lock()
+		defer m.warnMu.Unlock()
+		fmt.Fprintf(m.Verbose, format, args...)
+	}
+}
+
+// 把 init 选择的引用记录到缓存仓库的配置中
+func saveTrackedRef(repo *git.Repository, ref plumbing.ReferenceName) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Raw.Section(configSection).SetOption("ref", ref.String())
+	return repo.SetConfig(cfg)
+}
+
+// 读取 init 记录的引用；没有记录时跟踪克隆时检出的分支，即当时远程的默认分支，最后才使用 main
+func trackedRef(repo *git.Repository) plumbing.ReferenceName {
+	if ref := recordedRef(repo); ref != "" {
+		return ref
+	}
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		return head.Name()
+	}
+	return plumbing.NewBranchReferenceName("main")
+}
+
+// init 记录的引用，没有记录时返回空
+func recordedRef(repo *git.Repository) plumbing.ReferenceName {
+	cfg, err := repo.Config()
+	if err != nil {
+		return ""
+	}
+	return plumbing.ReferenceName(cfg.Raw.Section(configSection).Option("ref"))
+}
+
+// 明确要比较的引用的短名称：Branch 优先，其次是 init 记录的引用；都没有时返回空
+func (m *Manager) explicitRef(repo *git.Repository) string {
+	if m.Branch != "" {
+		return m.Branch
+	}
+	return recordedRef(repo).Short()
+}
+
+// Status 比较使用的远程引用：Branch 指定的分支或标签优先，其次是 init 记录的引用，
+// 然后是远程 HEAD 指向的默认分支，最后同 trackedRef
+func (m *Manager) statusRef(repo *git.Repository, refs []*plumbing.Reference) plumbing.ReferenceName {
+	if m.Branch != "" {
+		branch := plumbing.NewBranchReferenceName(m.Branch)
+		tag := plumbing.NewTagReferenceName(m.Branch)
+		if findRemoteHash(refs, branch).IsZero() && !findRemoteHash(refs, tag).IsZero() {
+			return tag
+		}
+		return branch
+	}
+	if ref := recordedRef(repo); ref != "" {
+		return ref
+	}
+	if ref := remoteDefaultBranch(refs); ref != "" {
+		return ref
+	}
+	return trackedRef(repo)
+}
+
+// 远程 HEAD 符号引用指向的分支，远程没有公布时返回空
+func remoteDefaultBranch(refs []*plumbing.Reference) plumbing.ReferenceName {
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference && ref.Target().IsBranch() {
+			return ref.Target()
+		}
+	}
+	return ""
+}
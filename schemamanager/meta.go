@@ -0,0 +1,15 @@
+package schemamanager
+
+import "schema-manager/schemamanager/hl"
+
+// Metadata 读取每个文件头部声明的元数据，结果和 files 一一对应；没有可识别头部的文件是零值。
+// 设置了 Ref 时读取该版本中的内容
+func (m *Manager) Metadata(files []File) ([]hl.Meta, error) {
+	return parallel(m.jobs(), len(files), func(i int) (hl.Meta, error) {
+		data, err := m.ReadFile(files[i].Path)
+		if err != nil {
+			return hl.Meta{}, err
+		}
+		return hl.ParseMeta(data), nil
+	})
+}
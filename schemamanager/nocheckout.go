@@ -0,0 +1,35 @@
+package schemamanager
+
+import (
+	"errors"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// ErrNoCheckout 表示操作需要工作区中的文件，而缓存克隆时没有检出
+var ErrNoCheckout = errors.New("not available for a cache cloned with 'init --no-checkout'")
+
+// IsNoCheckout 报告缓存是否是克隆时没有检出文件的仓库；此时和裸仓库一样，List、Search 和 ReadFile
+// 从 HEAD 提交的树中读取文件，update 只移动分支而不检出
+func (m *Manager) IsNoCheckout() bool {
+	repo, err := git.PlainOpen(m.CacheDir)
+	if err != nil {
+		return false
+	}
+	return noCheckout(repo)
+}
+
+func noCheckout(repo *git.Repository) bool {
+	cfg, err := repo.Config()
+	return err == nil && cfg.Raw.Section(configSection).Options.Get("nocheckout") == "true"
+}
+
+// 记录克隆时没有检出，之后的读取改为使用提交树
+func saveNoCheckout(repo *git.Repository) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Raw.Section(configSection).SetOption("nocheckout", "true")
+	return repo.SetConfig(cfg)
+}
@@ -0,0 +1,128 @@
+package schemamanager
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// Pin 把工作区检出到 rev 对应的提交（分离 HEAD），并记录在缓存仓库的配置中；
+// 之后 Status 比较 HEAD 和固定的提交，Update 不再拉取。工作区有未提交的修改时拒绝执行
+func (m *Manager) Pin(rev string) (plumbing.Hash, error) {
+	repo, err := m.open()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := m.requireWorktree("pin"); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolving %q: %w (run 'fetch' first if the commit is new)", rev, err)
+	}
+	// 标签等需要剥离到提交
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("%q is not a commit: %w", rev, err)
+	}
+
+	// 分离 HEAD 后无法再从 HEAD 得知跟踪的分支，先记录下来供 Unpin 使用
+	if recordedRef(repo) == "" {
+		if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+			if err := saveTrackedRef(repo, head.Name()); err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("saving tracked branch: %w", err)
+			}
+		}
+	}
+	if err := m.checkoutClean(repo, &git.CheckoutOptions{Hash: commit.Hash}); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := setRepoOption(repo, "pin", commit.Hash.String()); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("saving pin: %w", err)
+	}
+	return commit.Hash, nil
+}
+
+// Unpin 删除固定的提交并检出跟踪的分支，之后可以用 Update 拉取到最新；跟踪的是标签时检出该标签
+func (m *Manager) Unpin() (plumbing.ReferenceName, error) {
+	repo, err := m.open()
+	if err != nil {
+		return "", err
+	}
+	if err := m.requireWorktree("unpin"); err != nil {
+		return "", err
+	}
+	if pinnedHash(repo).IsZero() {
+		return "", fmt.Errorf("cache is not pinned")
+	}
+
+	ref := trackedRef(repo)
+	opts := &git.CheckoutOptions{Branch: ref}
+	if ref.IsTag() {
+		tag, err := repo.Tag(ref.Short())
+		if err != nil {
+			return "", fmt.Errorf("reading tag %s: %w", ref.Short(), err)
+		}
+		hash := tag.Hash()
+		if obj, err := repo.TagObject(hash); err == nil {
+			hash = obj.Target
+		}
+		opts = &git.CheckoutOptions{Hash: hash}
+	}
+	if err := m.checkoutClean(repo, opts); err != nil {
+		return "", err
+	}
+	if err := setRepoOption(repo, "pin", ""); err != nil {
+		return "", fmt.Errorf("removing pin: %w", err)
+	}
+	return ref, nil
+}
+
+// 工作区没有未提交的修改时才检出，避免覆盖用户的改动
+func (m *Manager) checkoutClean(repo *git.Repository, opts *git.CheckoutOptions) error {
+	changes, err := m.LocalChanges()
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if c.Status != "untracked" {
+			return fmt.Errorf("cache has uncommitted changes (%s %s); discard them first", c.Status, c.Path)
+		}
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("getting worktree: %w", err)
+	}
+	// 稀疏检出的缓存只检出记录的目录
+	opts.SparseCheckoutDirectories = sparseDirs(repo)
+	if err := w.Checkout(opts); err != nil {
+		return fmt.Errorf("checking out: %w", err)
+	}
+	return nil
+}
+
+// 记录的固定提交，没有固定时为零值
+func pinnedHash(repo *git.Repository) plumbing.Hash {
+	cfg, err := repo.Config()
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return plumbing.NewHash(cfg.Raw.Section(configSection).Option("pin"))
+}
+
+// 设置缓存仓库配置中的一个选项，value 为空时删除
+func setRepoOption(repo *git.Repository, key, value string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	section := cfg.Raw.Section(configSection)
+	if value == "" {
+		section.RemoveOption(key)
+	} else {
+		section.SetOption(key, value)
+	}
+	return repo.SetConfig(cfg)
+}
@@ -0,0 +1,61 @@
+package schemamanager
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-git/go-git/v6/plumbing/transport"
+)
+
+// 访问 repoURL 使用的代理：Proxy 优先，否则按 HTTPS_PROXY、HTTP_PROXY 和 NO_PROXY 环境变量选择；
+// 只有 HTTP 和 HTTPS 仓库使用代理，没有配置时直接连接
+func (m *Manager) proxy(repoURL string) (transport.ProxyOptions, error) {
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return transport.ProxyOptions{}, err
+	}
+	if ep.Protocol != "http" && ep.Protocol != "https" {
+		return transport.ProxyOptions{}, nil
+	}
+
+	var proxyURL *url.URL
+	if m.Proxy != "" {
+		proxyURL, err = url.Parse(m.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return transport.ProxyOptions{}, fmt.Errorf("invalid proxy URL %q", redactURL(m.Proxy))
+		}
+	} else {
+		req, err := http.NewRequest(http.MethodGet, ep.String(), nil)
+		if err != nil {
+			return transport.ProxyOptions{}, err
+		}
+		proxyURL, err = http.ProxyFromEnvironment(req)
+		if err != nil {
+			return transport.ProxyOptions{}, fmt.Errorf("invalid proxy in environment: %w", err)
+		}
+		if proxyURL == nil {
+			return transport.ProxyOptions{}, nil
+		}
+	}
+
+	// 地址中的用户名和密码单独传给 go-git
+	opts := transport.ProxyOptions{}
+	if user := proxyURL.User; user != nil {
+		opts.Username = user.Username()
+		opts.Password, _ = user.Password()
+		proxyURL.User = nil
+	}
+	opts.URL = proxyURL.String()
+	m.debugf("using proxy %s for %s\n", opts.URL, ep.Host)
+	return opts, nil
+}
+
+// 隐藏地址中的密码，用于错误信息
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "****"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,207 @@
+package schemamanager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing/protocol"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	githttp "github.com/go-git/go-git/v6/plumbing/transport/http"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// Retry-After 不超过这个时间时等待后重试，更长时直接报告
+const maxRateLimitWait = 30 * time.Second
+
+// RateLimitError 表示远程（通常是 GitHub）因为请求过多拒绝了请求。
+// 它同时被视为 ErrRemoteUnavailable，status 在有远程引用缓存时退回使用缓存
+type RateLimitError struct {
+	Host string
+	// RetryAfter 是服务器要求的等待时间，来自 Retry-After 或 X-RateLimit-Reset，未知时为 0
+	RetryAfter time.Duration
+	Err        error
+	// 没有使用 Token 时提示认证可以提高限额
+	anonymous bool
+}
+
+func (e *RateLimitError) Error() string {
+	name := e.Host
+	if isGitHub(e.Host) {
+		name = "GitHub"
+	}
+	msg := name + " rate limit exceeded"
+	if e.RetryAfter > 0 {
+		msg += ", retry after " + e.RetryAfter.Round(time.Second).String()
+	} else {
+		msg += "; try again later"
+	}
+	if e.anonymous && isGitHub(e.Host) {
+		msg += " (authenticated requests get a higher limit; set --token or OPENCMD_TOKEN)"
+	}
+	return msg
+}
+
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+func (e *RateLimitError) Is(target error) bool { return target == ErrRemoteUnavailable }
+
+func isGitHub(host string) bool {
+	return host == "github.com" || strings.HasSuffix(host, ".github.com")
+}
+
+// go-git 的 HTTP 错误不包含响应头，由 NewHTTPTransport 的 RoundTripper 记录每个主机最近一次限流响应
+type rateLimitHit struct {
+	at         time.Time
+	retryAfter time.Duration
+}
+
+// 一个 Manager 的网络操作中记录到的限流响应
+type rateLimitLog struct {
+	mu   sync.Mutex
+	hits map[string]rateLimitHit
+}
+
+func (l *rateLimitLog) record(host string, hit rateLimitHit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.hits == nil {
+		l.hits = map[string]rateLimitHit{}
+	}
+	l.hits[host] = hit
+}
+
+// 取出 since 之后最近的一条记录并删除它
+func (l *rateLimitLog) take(since time.Time) (string, rateLimitHit, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	host, hit := "", rateLimitHit{}
+	for h, v := range l.hits {
+		if !v.at.Before(since) && v.at.After(hit.at) {
+			host, hit = h, v
+		}
+	}
+	if host == "" {
+		return "", rateLimitHit{}, false
+	}
+	delete(l.hits, host)
+	return host, hit, true
+}
+
+type rateLimitLogKey struct{}
+
+// 设置了 DetectRateLimits 时在 ctx 中带上 m 的记录，请求经过 NewHTTPTransport 时写入其中
+func (m *Manager) recordRateLimits(ctx context.Context) context.Context {
+	if !m.DetectRateLimits {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimitLogKey{}, &m.rateLimits)
+}
+
+// NewHTTPTransport 返回 http 和 https 使用的 go-git 传输，它把限流响应记录到发起请求的 Manager 中，
+// 让 RateLimitError 带上主机和 Retry-After。go-git 按协议全局选择传输，需要由应用注册：
+//
+//	transport.Register("https", schemamanager.NewHTTPTransport())
+//
+// 端点设置了代理、CA 证书或跳过证书校验时交给 go-git 默认的传输处理，这时只能从 429 状态码识别限流
+func NewHTTPTransport() transport.Transport {
+	return httpTransport{
+		recording: githttp.NewTransport(&githttp.TransportOptions{
+			Client: &http.Client{Transport: rateLimitRecorder{base: http.DefaultTransport}},
+		}),
+		plain: githttp.NewTransport(nil),
+	}
+}
+
+type httpTransport struct {
+	recording, plain transport.Transport
+}
+
+func (t httpTransport) NewSession(st storage.Storer, ep *transport.Endpoint, auth transport.AuthMethod) (transport.Session, error) {
+	// go-git 只能在底层是 *http.Transport 时应用这些选项
+	if len(ep.CaBundle) > 0 || ep.InsecureSkipTLS || ep.Proxy.URL != "" {
+		return t.plain.NewSession(st, ep, auth)
+	}
+	return t.recording.NewSession(st, ep, auth)
+}
+
+func (t httpTransport) SupportedProtocols() []protocol.Version {
+	return t.recording.SupportedProtocols()
+}
+
+type rateLimitRecorder struct {
+	base http.RoundTripper
+}
+
+func (r rateLimitRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := r.base.RoundTrip(req)
+	if err != nil || !rateLimitResponse(res) {
+		return res, err
+	}
+	if l, ok := req.Context().Value(rateLimitLogKey{}).(*rateLimitLog); ok {
+		l.record(req.URL.Host, rateLimitHit{at: time.Now(), retryAfter: retryAfter(res.Header)})
+	}
+	return res, err
+}
+
+// 429，或 GitHub 用 403 加限额头表示的限流
+func rateLimitResponse(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return res.Header.Get("X-RateLimit-Remaining") == "0" || res.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// Retry-After 可以是秒数或 HTTP 日期；没有时用 X-RateLimit-Reset 的时间戳
+func retryAfter(h http.Header) time.Duration {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(time.Until(t), 0)
+		}
+	}
+	if v := h.Get("X-RateLimit-Reset"); v != "" {
+		if epoch, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return max(time.Until(time.Unix(epoch, 0)), 0)
+		}
+	}
+	return 0
+}
+
+// 如果 err 是 since 之后的请求被限流造成的，返回对应的 *RateLimitError
+func (m *Manager) rateLimited(err error, since time.Time) *RateLimitError {
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return rl
+	}
+	httpErr := httpError(err)
+	tooMany := httpErr != nil && httpErr.StatusCode() == http.StatusTooManyRequests
+	if !tooMany && !errors.Is(err, transport.ErrAuthorizationFailed) {
+		return nil
+	}
+
+	host, hit, recorded := m.rateLimits.take(since)
+	switch {
+	case recorded:
+		wait := max(hit.retryAfter-time.Since(hit.at), 0)
+		return &RateLimitError{Host: host, RetryAfter: wait, Err: err, anonymous: m.Token == ""}
+	case tooMany:
+		// 没有记录到响应（例如没有注册 NewHTTPTransport），只知道是 429
+		host := ""
+		if httpErr.URL != nil {
+			host = httpErr.URL.Host
+		}
+		return &RateLimitError{Host: host, Err: err, anonymous: m.Token == ""}
+	}
+	// 没有限流记录的 403 是真正的权限问题
+	return nil
+}
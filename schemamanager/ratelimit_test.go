@@ -0,0 +1,138 @@
+package schemamanager
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	githttp "github.com/go-git/go-git/v6/plumbing/transport/http"
+)
+
+func TestRetryAfter(t *testing.T) {
+	future := time.Now().Add(90 * time.Second)
+	tests := []struct {
+		name   string
+		header http.Header
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"none", http.Header{}, 0, 0},
+		{"seconds", http.Header{"Retry-After": {"7"}}, 7 * time.Second, 7 * time.Second},
+		{"http date", http.Header{"Retry-After": {future.UTC().Format(http.TimeFormat)}}, 80 * time.Second, 90 * time.Second},
+		{"past date", http.Header{"Retry-After": {time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}}, 0, 0},
+		{"reset epoch", http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(future.Unix(), 10)}}, 80 * time.Second, 90 * time.Second},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header); got < tt.min || got > tt.max {
+			t.Errorf("%s: retryAfter = %s, want between %s and %s", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+// 记录只属于发起请求的 Manager，取出后删除，早于 since 的记录不算
+func TestRateLimitedUsesManagerLog(t *testing.T) {
+	m, other := New(t.TempDir()), New(t.TempDir())
+	start := time.Now()
+	m.rateLimits.record("github.com", rateLimitHit{at: time.Now(), retryAfter: 20 * time.Second})
+
+	if rl := other.rateLimited(transport.ErrAuthorizationFailed, start); rl != nil {
+		t.Errorf("another manager reported %v", rl)
+	}
+	if rl := m.rateLimited(transport.ErrAuthorizationFailed, time.Now().Add(time.Second)); rl != nil {
+		t.Errorf("a hit before since was reported: %v", rl)
+	}
+
+	rl := m.rateLimited(transport.ErrAuthorizationFailed, start)
+	if rl == nil {
+		t.Fatal("rateLimited = nil for a recorded hit")
+	}
+	if rl.Host != "github.com" || rl.RetryAfter <= 0 || rl.RetryAfter > 20*time.Second {
+		t.Errorf("rateLimited = {Host: %q, RetryAfter: %s}, want github.com and at most 20s", rl.Host, rl.RetryAfter)
+	}
+	if !errors.Is(rl, ErrRemoteUnavailable) || !errors.Is(rl, transport.ErrAuthorizationFailed) {
+		t.Errorf("%v is not both ErrRemoteUnavailable and the original error", rl)
+	}
+	if rl := m.rateLimited(transport.ErrAuthorizationFailed, start); rl != nil {
+		t.Errorf("the hit was reported twice: %v", rl)
+	}
+}
+
+// 只有通过 recordRateLimits 的 ctx 发出的请求才会被记录
+func TestRateLimitRecorder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	host := srv.Listener.Addr().String()
+	client := &http.Client{Transport: rateLimitRecorder{base: http.DefaultTransport}}
+
+	get := func(m *Manager) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(m.recordRateLimits(t.Context()), http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	off := New(t.TempDir())
+	get(off)
+	if len(off.rateLimits.hits) != 0 {
+		t.Errorf("recorded %v without DetectRateLimits", off.rateLimits.hits)
+	}
+
+	m := New(t.TempDir())
+	m.DetectRateLimits = true
+	start := time.Now()
+	get(m)
+	gotHost, hit, ok := m.rateLimits.take(start)
+	if !ok || gotHost != host || hit.retryAfter != 7*time.Second {
+		t.Errorf("take = %q, %+v, %v; want %q with 7s", gotHost, hit, ok, host)
+	}
+}
+
+// 注册 NewHTTPTransport 后，GitHub 风格的 403 限流响应被报告为 RateLimitError 而不是权限错误
+func TestRemoteRefsRateLimited(t *testing.T) {
+	transport.Register("http", NewHTTPTransport())
+	defer transport.Register("http", githttp.NewTransport(nil))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	m := New(t.TempDir())
+	m.RepoURL = srv.URL + "/commands.git"
+	m.DetectRateLimits = true
+	_, err := m.RemoteRefs(t.Context())
+	var rl *RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("RemoteRefs error = %v, want *RateLimitError", err)
+	}
+	u, _ := url.Parse(srv.URL)
+	if rl.Host != u.Host || rl.RetryAfter < 59*time.Minute {
+		t.Errorf("RateLimitError = {Host: %q, RetryAfter: %s}, want %q and about 1h", rl.Host, rl.RetryAfter, u.Host)
+	}
+	if !errors.Is(err, ErrRemoteUnavailable) {
+		t.Errorf("%v is not ErrRemoteUnavailable", err)
+	}
+
+	// 没有启用时同样的响应只是权限错误
+	m.DetectRateLimits = false
+	if _, err := m.RemoteRefs(t.Context()); errors.As(err, &rl) {
+		t.Errorf("RemoteRefs without DetectRateLimits = %v, want a plain authorization error", err)
+	}
+}
@@ -0,0 +1,63 @@
+package schemamanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// 上次查询到的远程引用，RemoteMaxAge 内 Status 直接使用而不访问网络
+type remoteCache struct {
+	URL string `json:"url"`
+	// Want 是查询时明确指定的引用，为空表示 Ref 是远程的默认分支
+	Want    string    `json:"want"`
+	Ref     string    `json:"ref"`
+	Hash    string    `json:"hash"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// DefaultRemoteCachePath 返回缓存目录旁的远程引用缓存文件路径，默认缓存对应 ~/.opencmd/remote.json
+func DefaultRemoteCachePath(cacheDir string) string {
+	return filepath.Join(filepath.Dir(cacheDir), "remote.json")
+}
+
+// 读取 url 上按 want 查询到的缓存记录；文件缺失、损坏或记录的是其他远程或引用时返回 nil
+func (m *Manager) loadRemoteCache(url, want string) *remoteCache {
+	if m.RemoteCachePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.RemoteCachePath)
+	if err != nil {
+		return nil
+	}
+	var c remoteCache
+	if json.Unmarshal(data, &c) != nil || c.URL != url || c.Want != want || c.Hash == "" {
+		return nil
+	}
+	return &c
+}
+
+// 记录本次查询到的远程引用；写入失败只给出警告
+func (m *Manager) saveRemoteCache(url, want string, ref plumbing.ReferenceName, hash plumbing.Hash) {
+	if m.RemoteCachePath == "" || hash.IsZero() {
+		return
+	}
+	data, err := json.Marshal(remoteCache{URL: url, Want: want, Ref: ref.String(), Hash: hash.String(), Fetched: time.Now()})
+	if err == nil {
+		err = os.WriteFile(m.RemoteCachePath, data, 0644)
+	}
+	if err != nil {
+		m.warnf("Warning: saving remote ref cache: %v\n", err)
+	}
+}
+
+// 报告 err 是否说明当前无法访问远程（包括被限流），此时可以退回使用缓存的远程引用
+func offline(err error) bool {
+	var rl *RateLimitError
+	return transient(err) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &rl)
+}
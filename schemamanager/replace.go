@@ -0,0 +1,174 @@
+package schemamanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Replacement 是 Replace 在一个文件中做的替换
+type Replacement struct {
+	Path string `json:"path"`
+	// Count 是文件中被替换的匹配数
+	Count int `json:"count"`
+	// Patch 是替换前后的统一格式差异
+	Patch string `json:"patch"`
+}
+
+// 差异中每处改动前后保留的上下文行数
+const patchContext = 3
+
+// Replace 逐行把内容匹配 pattern 的部分替换为 repl，返回每个有改动的文件的差异，按路径排序。
+// 替换按 regexp.ReplaceAllString 的语义，repl 中可以用 $1、${name} 引用分组；opts.Fixed 时 repl 按原样插入。
+// write 为 false 时只预览，不修改文件；为 true 时把结果写回工作区
+func (m *Manager) Replace(pattern, repl string, opts SearchOptions, write bool) ([]Replacement, error) {
+	if !m.Exists() {
+		return nil, ErrNotInitialized
+	}
+	if opts.Fuzzy || opts.Invert {
+		return nil, errors.New("replace needs a regular expression match; fuzzy and inverted matching are not supported")
+	}
+	if write {
+		if err := m.requireWorktree("replace --write"); err != nil {
+			return nil, err
+		}
+		if m.Ref != "" {
+			return nil, fmt.Errorf("cannot write replacements into revision %s; only the working tree can be changed", m.Ref)
+		}
+	}
+	regex, err := CompilePattern(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先用内容搜索找出有匹配的文件，二进制文件已经被跳过
+	opts.Content, opts.Limit = true, 0
+	matches, err := m.Search(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Replacement
+	for i, match := range matches {
+		if i > 0 && matches[i-1].Path == match.Path {
+			continue
+		}
+		data, err := m.ReadFile(match.Path)
+		if err != nil {
+			return result, err
+		}
+		updated, r := replaceLines(match.Path, string(data), regex, repl, opts.Fixed)
+		if r.Count == 0 {
+			continue
+		}
+		if write {
+			path, err := m.Resolve(match.Path)
+			if err != nil {
+				return result, err
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return result, err
+			}
+			if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+				return result, fmt.Errorf("writing %s: %w", match.Path, err)
+			}
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// 对 content 逐行替换，返回新内容和差异；行尾的 \r 不参与匹配，和内容搜索一致
+func replaceLines(relPath, content string, regex *regexp.Regexp, repl string, literal bool) (string, Replacement) {
+	r := Replacement{Path: relPath}
+	trailing := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	replaced := make([]string, len(lines))
+	for i, line := range lines {
+		text, cr := strings.CutSuffix(line, "\r")
+		n := len(regex.FindAllStringIndex(text, -1))
+		if n == 0 {
+			replaced[i] = line
+			continue
+		}
+		r.Count += n
+		if literal {
+			text = regex.ReplaceAllLiteralString(text, repl)
+		} else {
+			text = regex.ReplaceAllString(text, repl)
+		}
+		if cr {
+			text += "\r"
+		}
+		replaced[i] = text
+	}
+	if r.Count == 0 {
+		return content, r
+	}
+
+	r.Patch = unifiedPatch(relPath, lines, replaced)
+	updated := strings.Join(replaced, "\n")
+	if trailing {
+		updated += "\n"
+	}
+	return updated, r
+}
+
+// 生成逐行替换前后的统一格式差异。替换不增删行，但 repl 中的换行会让一行变成多行
+func unifiedPatch(relPath string, before, after []string) string {
+	var changed []int
+	for i := range before {
+		if before[i] != after[i] {
+			changed = append(changed, i)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", relPath, relPath)
+	// offset 是当前块之前新文件比旧文件多出的行数
+	offset := 0
+	for start := 0; start < len(changed); {
+		// 相邻改动的上下文重叠时合并成一个块
+		end := start
+		for end+1 < len(changed) && changed[end+1]-changed[end] <= 2*patchContext {
+			end++
+		}
+		lo := max(changed[start]-patchContext, 0)
+		hi := min(changed[end]+patchContext+1, len(before))
+
+		var body strings.Builder
+		newLen := 0
+		for i := lo; i < hi; {
+			if before[i] == after[i] {
+				fmt.Fprintf(&body, " %s\n", before[i])
+				newLen++
+				i++
+				continue
+			}
+			// 连续改动的行先输出全部旧行，再输出全部新行
+			j := i
+			for j < hi && before[j] != after[j] {
+				fmt.Fprintf(&body, "-%s\n", before[j])
+				j++
+			}
+			for k := i; k < j; k++ {
+				for _, line := range strings.Split(after[k], "\n") {
+					fmt.Fprintf(&body, "+%s\n", line)
+					newLen++
+				}
+			}
+			i = j
+		}
+
+		oldLen := hi - lo
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", lo+1, oldLen, lo+1+offset, newLen)
+		b.WriteString(body.String())
+		offset += newLen - oldLen
+		start = end + 1
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package schemamanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+)
+
+// ErrInvalidRepoURL 表示仓库地址无法解析或使用了不支持的协议
+var ErrInvalidRepoURL = errors.New("invalid repository URL")
+
+// ErrRefNotFound 表示远程没有要求的分支或标签
+var ErrRefNotFound = errors.New("no such branch or tag")
+
+// 错误信息中最多列出的分支和标签数
+const maxListedRefs = 10
+
+// ValidateRepoURL 在访问网络前检查仓库地址：必须能够解析，协议是 https、http、ssh、git 或本地路径，
+// 网络地址必须带主机名，本地路径必须存在
+func ValidateRepoURL(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return fmt.Errorf("%w: the URL is empty", ErrInvalidRepoURL)
+	}
+	ep, err := transport.NewEndpoint(raw)
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidRepoURL, redactURL(raw), err)
+	}
+	switch ep.Protocol {
+	case "https", "http", "ssh", "git":
+		if ep.Host == "" {
+			return fmt.Errorf("%w %q: missing host name", ErrInvalidRepoURL, redactURL(raw))
+		}
+	case "file":
+		if _, err := os.Stat(ep.Path); err != nil {
+			return fmt.Errorf("%w %q: no repository at %s", ErrInvalidRepoURL, raw, ep.Path)
+		}
+	default:
+		return fmt.Errorf("%w %q: unsupported protocol %q (use https, http, ssh, git or a local path)", ErrInvalidRepoURL, redactURL(raw), ep.Protocol)
+	}
+	return nil
+}
+
+// 远程没有 name 时的错误，列出可用的分支和标签帮助改正拼写
+func refNotFound(name, repoURL string, refs []*plumbing.Reference) error {
+	var branches, tags []string
+	for _, ref := range refs {
+		switch {
+		case ref.Name().IsBranch():
+			branches = append(branches, ref.Name().Short())
+		case ref.Name().IsTag() && !strings.HasSuffix(ref.Name().String(), "^{}"):
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+	msg := fmt.Sprintf("%q on %s", name, redactURL(repoURL))
+	if len(branches) > 0 {
+		msg += "; available branches: " + listRefs(branches)
+	}
+	if len(tags) > 0 {
+		msg += "; tags: " + listRefs(tags)
+	}
+	return fmt.Errorf("%w %s", ErrRefNotFound, msg)
+}
+
+func listRefs(names []string) string {
+	sort.Strings(names)
+	if len(names) > maxListedRefs {
+		return strings.Join(names[:maxListedRefs], ", ") + fmt.Sprintf(" and %d more", len(names)-maxListedRefs)
+	}
+	return strings.Join(names, ", ")
+}
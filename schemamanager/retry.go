@@ -0,0 +1,114 @@
+package schemamanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	githttp "github.com/go-git/go-git/v6/plumbing/transport/http"
+)
+
+// 默认的重试次数和首次重试前的等待时间
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = time.Second
+)
+
+// ErrRemoteUnavailable 表示暂时无法访问远程仓库，例如网络中断、DNS 解析失败、服务器出错或超时
+var ErrRemoteUnavailable = errors.New("remote repository is unreachable")
+
+// 网络操作的错误去掉敏感信息，无法访问远程时同时标记为 ErrRemoteUnavailable
+func (m *Manager) remoteErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return m.redact(err)
+	}
+	if offline(err) {
+		return fmt.Errorf("%w: %w", ErrRemoteUnavailable, m.redact(err))
+	}
+	return m.redact(err)
+}
+
+// 对网络操作 fn 进行重试，等待时间每次翻倍；只重试网络类错误，认证失败等错误直接返回。
+// 被限流时返回 *RateLimitError，服务器要求的等待时间较短时按它等待后重试，否则不再重试。
+// fn 应使用传入的 ctx，其中带有记录限流响应的位置
+func (m *Manager) retry(ctx context.Context, what string, fn func(ctx context.Context) error) error {
+	delay := m.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+	opCtx := m.recordRateLimits(ctx)
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err := fn(opCtx)
+		if err == nil {
+			return nil
+		}
+		wait := delay
+		if rl := m.rateLimited(err, start); rl != nil {
+			if attempt >= m.Retries || rl.RetryAfter <= 0 || rl.RetryAfter > maxRateLimitWait {
+				return rl
+			}
+			err, wait = rl, rl.RetryAfter
+		} else if attempt >= m.Retries || !transient(err) {
+			return err
+		}
+
+		m.debugf("%s failed (%v); retrying in %s (attempt %d/%d)\n", what, m.redact(err), wait, attempt+1, m.Retries)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// 报告 err 是否可能是暂时的网络故障
+func transient(err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrRepositoryNotFound):
+		return false
+	case errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED):
+		return true
+	}
+
+	// 服务器暂时不可用
+	if httpErr := httpError(err); httpErr != nil {
+		code := httpErr.StatusCode()
+		return code == 429 || code >= 500
+	}
+
+	// DNS 解析失败、连接超时、TLS 握手中断等
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// 取出 HTTP 状态错误；go-git 把它包在没有 Unwrap 的 plumbing.UnexpectedError 中
+func httpError(err error) *githttp.Err {
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		err = unexpected.Err
+	}
+	var httpErr *githttp.Err
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	return nil
+}
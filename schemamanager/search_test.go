@@ -0,0 +1,41 @@
+package schemamanager
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// 超过 bufio.Scanner 默认 64 KB 的行照常匹配，之后的行也会被搜索
+func TestSearchReaderLongLine(t *testing.T) {
+	src := "cmd a {}\n" + strings.Repeat("x", 200<<10) + "needle\n" + "needle again\n"
+	matches, err := searchReader(strings.NewReader(src), regexp.MustCompile("needle"), SearchOptions{})
+	if err != nil {
+		t.Fatalf("searchReader: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Line != 2 || matches[1].Line != 3 {
+		t.Fatalf("matches = %+v, want lines 2 and 3", lineNumbers(matches))
+	}
+}
+
+// 超过 maxLineLength 的行停止读取，返回之前的匹配和 *partialReadError
+func TestSearchReaderTooLong(t *testing.T) {
+	src := "needle\n" + strings.Repeat("x", maxLineLength+1) + "\nneedle\n"
+	matches, err := searchReader(strings.NewReader(src), regexp.MustCompile("needle"), SearchOptions{})
+	var partial *partialReadError
+	if !errors.As(err, &partial) || partial.Line != 2 {
+		t.Fatalf("err = %v, want a partial read error at line 2", err)
+	}
+	if len(matches) != 1 || matches[0].Line != 1 {
+		t.Fatalf("matches = %v, want line 1", lineNumbers(matches))
+	}
+}
+
+func lineNumbers(matches []Match) []int {
+	lines := make([]int, len(matches))
+	for i, m := range matches {
+		lines[i] = m.Line
+	}
+	return lines
+}
@@ -0,0 +1,114 @@
+package schemamanager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ChangedSince 返回 since 之后的提交中改动过的 .hl 文件路径，去重并排序，包括已删除的文件。
+// since 可以是 git 引用（标签、分支或提交），也可以是 RFC3339 时间或 2006-01-02 格式的日期
+func (m *Manager) ChangedSince(since string) ([]string, error) {
+	repo, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("getting HEAD: %w", err)
+	}
+
+	// 先按日期解析，否则当作引用：只保留从 HEAD 可达但从该引用不可达的提交
+	var include func(c *object.Commit) bool
+	if t, ok := parseSince(since); ok {
+		include = func(c *object.Commit) bool { return !c.Committer.When.Before(t) }
+	} else {
+		base, err := repo.ResolveRevision(plumbing.Revision(since))
+		if err != nil {
+			return nil, fmt.Errorf("resolving %q: not a date or a known ref", since)
+		}
+		seen := map[plumbing.Hash]bool{}
+		if err := walkHistory(repo, *base, func(c *object.Commit) error {
+			seen[c.Hash] = true
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		include = func(c *object.Commit) bool { return !seen[c.Hash] }
+	}
+
+	changed := map[string]bool{}
+	err = walkHistory(repo, head.Hash(), func(c *object.Commit) error {
+		if !include(c) {
+			return nil
+		}
+		paths, err := touchedFiles(c)
+		if err != nil {
+			// 浅克隆最早的提交缺少父提交，无法比较
+			m.warnf("Warning: skipping commit %s: %v\n", c.Hash.String()[:8], err)
+			return nil
+		}
+		for _, p := range paths {
+			if m.IsSchemaFile(p) {
+				changed[p] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(changed))
+	for p := range changed {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func parseSince(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// 提交相对第一个父提交改动的文件，根提交的所有文件都算改动
+func touchedFiles(c *object.Commit) ([]string, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, ch := range changes {
+		if ch.From.Name != "" {
+			paths = append(paths, ch.From.Name)
+		}
+		if ch.To.Name != "" && ch.To.Name != ch.From.Name {
+			paths = append(paths, ch.To.Name)
+		}
+	}
+	return paths, nil
+}
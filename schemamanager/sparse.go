@@ -0,0 +1,134 @@
+package schemamanager
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// 规范化稀疏检出的目录：以 / 分隔、去掉首尾的 /，拒绝空路径和 ..
+func cleanSparseDirs(dirs []string) ([]string, error) {
+	cleaned := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		c := path.Clean(strings.Trim(strings.ReplaceAll(d, "\\", "/"), "/"))
+		if c == "." || c == ".." || strings.HasPrefix(c, "../") {
+			return nil, fmt.Errorf("invalid sparse checkout path %q: must be a directory inside the repository", d)
+		}
+		cleaned = append(cleaned, c)
+	}
+	return cleaned, nil
+}
+
+// 克隆时没有检出，只把 dirs 中的目录检出到工作区，并记录在缓存仓库的配置中供之后的检出使用
+func (m *Manager) checkoutSparse(repo *git.Repository, dirs []string) error {
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("getting HEAD: %w", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("reading HEAD commit: %w", err)
+	}
+	if err := checkSparseDirs(commit, dirs); err != nil {
+		return err
+	}
+
+	// 克隆标签时 HEAD 是分离的
+	opts := &git.CheckoutOptions{SparseCheckoutDirectories: dirs}
+	if head.Name().IsBranch() {
+		opts.Branch = head.Name()
+	} else {
+		opts.Hash = head.Hash()
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("getting worktree: %w", err)
+	}
+	if err := w.Checkout(opts); err != nil {
+		return fmt.Errorf("checking out %s: %w", strings.Join(dirs, ", "), err)
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	section := cfg.Raw.Section(configSection)
+	section.RemoveOption("sparse")
+	for _, d := range dirs {
+		section.AddOption("sparse", d)
+	}
+	return repo.SetConfig(cfg)
+}
+
+// 每个目录都必须存在于 commit 中，否则稀疏检出得到的是空工作区
+func checkSparseDirs(commit *object.Commit, dirs []string) error {
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("reading tree: %w", err)
+	}
+	var missing []string
+	for _, d := range dirs {
+		if _, err := tree.Tree(d); err != nil {
+			missing = append(missing, d)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("sparse checkout path(s) not found in %s: %s", commit.Hash.String()[:8], strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// 稀疏检出的目录，没有稀疏检出时为空
+func sparseDirs(repo *git.Repository) []string {
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil
+	}
+	return cfg.Raw.Section(configSection).Options.GetAll("sparse")
+}
+
+// SparseCheckout 返回 init --path 记录的稀疏检出目录，完整检出时为空
+func (m *Manager) SparseCheckout() []string {
+	repo, err := m.open()
+	if err != nil {
+		return nil
+	}
+	return sparseDirs(repo)
+}
+
+// 稀疏检出的缓存不能用 Pull，否则会检出全部文件；改为下载远程分支，确认可以快进后把工作区重置到新的提交
+func (m *Manager) pullSparse(repo *git.Repository, ref plumbing.ReferenceName, from plumbing.Hash, dirs []string) error {
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", ref.Short()), true)
+	if err != nil {
+		return fmt.Errorf("reading remote %s: %w", ref.Short(), err)
+	}
+	to := remoteRef.Hash()
+	if to == from {
+		return git.NoErrAlreadyUpToDate
+	}
+
+	head, err := repo.CommitObject(from)
+	if err != nil {
+		return fmt.Errorf("reading HEAD commit: %w", err)
+	}
+	target, err := repo.CommitObject(to)
+	if err != nil {
+		return fmt.Errorf("reading remote commit: %w", err)
+	}
+	if ok, err := head.IsAncestor(target); err != nil || !ok {
+		return git.ErrNonFastForwardUpdate
+	}
+	if err := checkSparseDirs(target, dirs); err != nil {
+		return err
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("getting worktree: %w", err)
+	}
+	return w.Reset(&git.ResetOptions{Commit: to, Mode: git.MergeReset, SparseDirs: dirs})
+}
@@ -0,0 +1,56 @@
+package schemamanager
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 统计中列出的最大文件数
+const largestFiles = 5
+
+// Stats 是缓存中 schema 集合的概况
+type Stats struct {
+	Files int   `json:"files"`
+	Size  int64 `json:"size"`
+	// ByDir 是每个顶层目录下的 .hl 文件数，根目录下的文件记在 "." 下
+	ByDir map[string]int `json:"byDir"`
+	// Largest 是按大小排列的最大几个文件
+	Largest []File `json:"largest"`
+	// Head 是缓存仓库当前的提交，设置了 Ref 时是该版本的提交，无法读取时为空
+	Head string `json:"head,omitempty"`
+}
+
+// Stats 遍历一次缓存并汇总文件数、大小、顶层目录分布和最大的文件
+func (m *Manager) Stats() (Stats, error) {
+	stats := Stats{ByDir: map[string]int{}}
+
+	files, err := m.List()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, f := range files {
+		stats.Files++
+		stats.Size += f.Size
+		top, _, found := strings.Cut(filepath.ToSlash(f.Path), "/")
+		if !found {
+			top = "."
+		}
+		stats.ByDir[top]++
+	}
+
+	largest := append([]File{}, files...)
+	sort.SliceStable(largest, func(i, j int) bool { return largest[i].Size > largest[j].Size })
+	if len(largest) > largestFiles {
+		largest = largest[:largestFiles]
+	}
+	stats.Largest = largest
+
+	if m.Ref != "" {
+		stats.Head, _ = m.refHash()
+	} else {
+		stats.Head, _ = m.headHash()
+	}
+	return stats, nil
+}
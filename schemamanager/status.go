@@ -0,0 +1,380 @@
+package schemamanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+)
+
+// StatusResult 是本地缓存和远程的比较结果
+type StatusResult struct {
+	// Ref 是比较使用的远程分支或标签
+	Ref plumbing.ReferenceName
+	// LocalHead 是本地 HEAD 指向的提交
+	LocalHead plumbing.Hash
+	// RemoteHash 是远程 Ref 指向的提交，远程没有该引用时为零值
+	RemoteHash plumbing.Hash
+	// OriginURL 是缓存仓库 origin 的地址
+	OriginURL string
+	// LocalDate 和 LocalSubject 是本地 HEAD 提交的作者时间和标题
+	LocalDate    time.Time
+	LocalSubject string
+	// BehindBy 是本地落后远程的提交数，远程提交尚未下载到本地时为 -1
+	BehindBy int
+	// LocalChanges 是工作区中未提交的修改
+	LocalChanges []LocalChange
+	// Pinned 是 pin 固定的提交，没有固定时为零值；固定时 UpToDate 比较的是 HEAD 和 Pinned，
+	// BehindBy 仍然是相对远程的落后提交数
+	Pinned plumbing.Hash
+	// Cached 为 true 时 RemoteHash 来自 CachedAt 记录的远程引用缓存，而不是本次查询
+	Cached   bool
+	CachedAt time.Time
+}
+
+// UpToDate 报告本地 HEAD 是否和远程一致；固定了提交时报告 HEAD 是否仍是固定的提交
+func (r StatusResult) UpToDate() bool {
+	if !r.Pinned.IsZero() {
+		return r.LocalHead == r.Pinned
+	}
+	return !r.RemoteHash.IsZero() && r.LocalHead == r.RemoteHash
+}
+
+// UpdateResult 是 Update 拉取后的变更概况
+type UpdateResult struct {
+	// UpToDate 为 true 时没有新的提交
+	UpToDate bool
+	// PinnedTag 为 true 时缓存固定在标签上，不会拉取
+	PinnedTag bool
+	// Pinned 是 pin 固定的提交，非零时不会拉取
+	Pinned plumbing.Hash
+	Ref    plumbing.ReferenceName
+	From   plumbing.Hash
+	To     plumbing.Hash
+	// Commits 和 Files 是拉取带来的提交数和变更文件数，无法统计时为 -1
+	Commits int
+	Files   int
+}
+
+// Status 比较本地 HEAD 和远程跟踪的分支或标签
+func (m *Manager) Status(ctx context.Context) (StatusResult, error) {
+	var result StatusResult
+
+	// 打开仓库
+	repo, err := m.open()
+	if err != nil {
+		return result, err
+	}
+
+	// 获取远程引用
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return result, fmt.Errorf("getting remote: %w", err)
+	}
+	if urls := remote.Config().URLs; len(urls) > 0 {
+		result.OriginURL = urls[0]
+	}
+
+	// 认证方式按缓存实际的 origin 地址选择
+	auth, err := m.auth(result.OriginURL)
+	if err != nil {
+		return result, fmt.Errorf("preparing credentials: %w", err)
+	}
+	proxy, err := m.proxy(result.OriginURL)
+	if err != nil {
+		return result, err
+	}
+	insecure := m.insecureTLS(result.OriginURL)
+
+	// 远程引用缓存未过期时不访问网络
+	want := m.explicitRef(repo)
+	cached := m.loadRemoteCache(result.OriginURL, want)
+	if cached != nil && m.RemoteMaxAge > 0 && time.Since(cached.Fetched) < m.RemoteMaxAge {
+		m.debugf("using cached remote %s from %s\n", cached.Ref, cached.Fetched.Format(time.RFC3339))
+		result.Ref = plumbing.ReferenceName(cached.Ref)
+		result.RemoteHash = plumbing.NewHash(cached.Hash)
+		result.Cached, result.CachedAt = true, cached.Fetched
+	} else {
+		// 获取远程分支信息，标签需要剥离到提交
+		var refs []*plumbing.Reference
+		err = m.retry(ctx, "listing remote refs", func(ctx context.Context) (err error) {
+			refs, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth, PeelingOption: git.AppendPeeled, ProxyOptions: proxy, InsecureSkipTLS: insecure})
+			return err
+		})
+		switch {
+		case err == nil:
+			// 只比较 HEAD 和远程末端的哈希，浅克隆同样适用
+			result.Ref = m.statusRef(repo, refs)
+			result.RemoteHash = findRemoteHash(refs, result.Ref)
+			m.debugf("remote %s: %d refs, %s is %s\n", result.OriginURL, len(refs), result.Ref, result.RemoteHash)
+			m.saveRemoteCache(result.OriginURL, want, result.Ref, result.RemoteHash)
+		case cached != nil && offline(err):
+			// 无法访问远程或被限流时退回使用上次的记录
+			result.Ref = plumbing.ReferenceName(cached.Ref)
+			var rl *RateLimitError
+			if errors.As(err, &rl) {
+				m.warnf("Warning: %v; using remote %s cached at %s\n", m.redact(err), result.Ref.Short(), cached.Fetched.Format(time.RFC3339))
+			} else {
+				m.warnf("Warning: remote is unreachable (%v); using remote %s cached at %s\n", m.redact(err), result.Ref.Short(), cached.Fetched.Format(time.RFC3339))
+			}
+			result.RemoteHash = plumbing.NewHash(cached.Hash)
+			result.Cached, result.CachedAt = true, cached.Fetched
+		default:
+			return result, fmt.Errorf("listing remote refs: %w", m.remoteErr(err))
+		}
+	}
+
+	// 获取本地HEAD
+	head, err := repo.Head()
+	if err != nil {
+		return result, fmt.Errorf("getting HEAD: %w", err)
+	}
+	result.LocalHead = head.Hash()
+
+	// 读取本地提交信息，说明缓存有多旧
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return result, fmt.Errorf("reading HEAD commit: %w", err)
+	}
+	result.LocalDate = commit.Author.When
+	result.Pinned = pinnedHash(repo)
+	result.LocalSubject, _, _ = strings.Cut(commit.Message, "\n")
+
+	// 工作区状态只用于提醒，读取失败不影响比较结果
+	result.LocalChanges, err = m.LocalChanges()
+	if err != nil {
+		m.warnf("Warning: checking local changes: %v\n", err)
+	}
+
+	// 沿远程历史统计落后的提交数，固定了提交时统计固定的提交落后多少；远程提交不在本地时先下载到
+	// 远程跟踪引用，不改动工作区；使用缓存的远程引用时不访问网络
+	base := result.LocalHead
+	if !result.Pinned.IsZero() {
+		base = result.Pinned
+	}
+	result.BehindBy = -1
+	if !result.Cached && !result.RemoteHash.IsZero() && base != result.RemoteHash {
+		if _, err := repo.CommitObject(result.RemoteHash); err != nil {
+			_ = m.retry(ctx, "fetching", func(ctx context.Context) error {
+				return fetchTracked(ctx, repo, result.Ref, auth, proxy, insecure)
+			})
+		}
+	}
+	if !result.RemoteHash.IsZero() {
+		if n, err := commitsBetween(repo, base, result.RemoteHash); err == nil {
+			result.BehindBy = n
+		}
+	}
+	return result, nil
+}
+
+// 把远程的 ref 下载到对应的远程跟踪引用
+func fetchTracked(ctx context.Context, repo *git.Repository, ref plumbing.ReferenceName, auth transport.AuthMethod, proxy transport.ProxyOptions, insecure bool) error {
+	dst := plumbing.NewRemoteReferenceName("origin", ref.Short())
+	if ref.IsTag() {
+		dst = ref
+	}
+	err := repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName:      "origin",
+		Auth:            auth,
+		ProxyOptions:    proxy,
+		InsecureSkipTLS: insecure,
+		RefSpecs:        []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", ref, dst))},
+		Tags:            git.NoTags,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return err
+}
+
+// Update 把远程最新提交拉取到缓存的工作区；裸仓库只更新跟踪的分支
+func (m *Manager) Update(ctx context.Context) (UpdateResult, error) {
+	var result UpdateResult
+
+	repo, err := m.open()
+	if err != nil {
+		return result, err
+	}
+
+	// 记录拉取前的 HEAD，用于统计变更
+	before, err := repo.Head()
+	if err != nil {
+		return result, fmt.Errorf("getting HEAD: %w", err)
+	}
+	result.From = before.Hash()
+
+	// 固定在提交或标签上时没有可拉取的内容
+	result.Ref = trackedRef(repo)
+	if result.Pinned = pinnedHash(repo); !result.Pinned.IsZero() {
+		return result, nil
+	}
+	if result.Ref.IsTag() {
+		result.PinnedTag = true
+		return result, nil
+	}
+
+	auth, err := m.auth(originURL(repo))
+	if err != nil {
+		return result, fmt.Errorf("preparing credentials: %w", err)
+	}
+	proxy, err := m.proxy(originURL(repo))
+	if err != nil {
+		return result, err
+	}
+	insecure := m.insecureTLS(originURL(repo))
+
+	if dirs := sparseDirs(repo); len(dirs) > 0 {
+		err = m.retry(ctx, "fetching", func(ctx context.Context) error {
+			return fetchTracked(ctx, repo, result.Ref, auth, proxy, insecure)
+		})
+		if err == nil {
+			err = m.pullSparse(repo, result.Ref, result.From, dirs)
+		}
+	} else if m.IsBare() || noCheckout(repo) {
+		// 裸仓库没有工作区可以合并，直接把远程分支下载到本地同名分支；没有检出的缓存同样只移动分支
+		err = m.retry(ctx, "fetching", func(ctx context.Context) error {
+			return fetchBare(ctx, repo, result.Ref, auth, proxy, insecure)
+		})
+		if head, herr := repo.Head(); err == nil && herr == nil && head.Hash() == result.From {
+			err = git.NoErrAlreadyUpToDate
+		}
+	} else {
+		w, werr := repo.Worktree()
+		if werr != nil {
+			return result, fmt.Errorf("getting worktree: %w", werr)
+		}
+		err = m.retry(ctx, "pulling", func(ctx context.Context) error {
+			return w.PullContext(ctx, &git.PullOptions{
+				RemoteName:      "origin",
+				Auth:            auth,
+				ProxyOptions:    proxy,
+				InsecureSkipTLS: insecure,
+				ReferenceName:   result.Ref,
+				SingleBranch:    true,
+				Progress:        m.Progress,
+			})
+		})
+	}
+	if err == git.NoErrAlreadyUpToDate {
+		result.UpToDate = true
+		result.To = result.From
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("pulling repository: %w", m.remoteErr(err))
+	}
+
+	after, err := repo.Head()
+	if err != nil {
+		return result, fmt.Errorf("getting HEAD: %w", err)
+	}
+	result.To = after.Hash()
+
+	result.Commits, result.Files, err = countChanges(repo, result.From, result.To)
+	if err != nil {
+		result.Commits, result.Files = -1, -1
+	}
+	return result, nil
+}
+
+// 把远程的分支强制下载到裸仓库中的同名本地分支，HEAD 随之指向新的提交
+func fetchBare(ctx context.Context, repo *git.Repository, ref plumbing.ReferenceName, auth transport.AuthMethod, proxy transport.ProxyOptions, insecure bool) error {
+	err := repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName:      "origin",
+		Auth:            auth,
+		ProxyOptions:    proxy,
+		InsecureSkipTLS: insecure,
+		RefSpecs:        []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))},
+		Tags:            git.NoTags,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return err
+}
+
+func originURL(repo *git.Repository) string {
+	remote, err := repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	return remote.Config().URLs[0]
+}
+
+// 在远程引用列表中查找目标引用的提交，附注标签取剥离后的哈希
+func findRemoteHash(refs []*plumbing.Reference, target plumbing.ReferenceName) plumbing.Hash {
+	var hash plumbing.Hash
+	for _, ref := range refs {
+		switch ref.Name().String() {
+		case target.String() + "^{}":
+			return ref.Hash()
+		case target.String():
+			hash = ref.Hash()
+		}
+	}
+	return hash
+}
+
+// 统计从 tip 可达但从 base 不可达的提交数
+func commitsBetween(repo *git.Repository, base, tip plumbing.Hash) (int, error) {
+	if base == tip {
+		return 0, nil
+	}
+	if _, err := repo.CommitObject(tip); err != nil {
+		return 0, err
+	}
+
+	// 先收集 base 的全部祖先
+	seen := map[plumbing.Hash]bool{}
+	if err := walkHistory(repo, base, func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := walkHistory(repo, tip, func(c *object.Commit) error {
+		if !seen[c.Hash] {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// 遍历 from 的历史；浅克隆的历史被截断，走到缺失的父提交时停止
+func walkHistory(repo *git.Repository, from plumbing.Hash, fn func(c *object.Commit) error) error {
+	iter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return err
+	}
+	err = iter.ForEach(fn)
+	if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return err
+	}
+	return nil
+}
+
+// 统计两个提交之间的提交数和变更文件数
+func countChanges(repo *git.Repository, from, to plumbing.Hash) (int, int, error) {
+	commits, err := commitsBetween(repo, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	changes, err := diffTrees(repo, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return commits, len(changes), nil
+}
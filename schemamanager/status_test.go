@@ -0,0 +1,89 @@
+package schemamanager
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// 浅克隆只有 HEAD 一个提交，远程的新提交在截断的历史之上，落后数仍然正确
+func TestStatusShallowBehind(t *testing.T) {
+	src := newFixtureRepo(t)
+	src.commit(map[string]string{"a.hl": "cmd a {}\n"})
+	parent := src.commit(map[string]string{"b.hl": "cmd b {}\n"})
+	head := src.commit(map[string]string{"c.hl": "cmd c {}\n"})
+
+	m := src.clone(t, 1)
+	repo, err := m.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 截断处在 HEAD 和它的父提交之间
+	if _, err := repo.CommitObject(parent); err == nil {
+		t.Fatal("depth-1 clone contains the parent of HEAD")
+	}
+
+	src.commit(map[string]string{"d.hl": "cmd d {}\n"})
+	tip := src.commit(map[string]string{"e.hl": "cmd e {}\n"})
+
+	result, err := m.Status(t.Context())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if result.LocalHead != head {
+		t.Errorf("LocalHead = %s, want %s", result.LocalHead, head)
+	}
+	if result.RemoteHash != tip {
+		t.Errorf("RemoteHash = %s, want %s", result.RemoteHash, tip)
+	}
+	if result.UpToDate() {
+		t.Error("UpToDate() = true for a cache two commits behind")
+	}
+	if result.BehindBy != 2 {
+		t.Errorf("BehindBy = %d, want 2", result.BehindBy)
+	}
+}
+
+// 截断处的父提交缺失时 walkHistory 正常停止，只统计本地已有的历史
+func TestCommitsBetweenTruncatedHistory(t *testing.T) {
+	src := newFixtureRepo(t)
+	for range 4 {
+		src.commit(nil)
+	}
+	m := src.clone(t, 2)
+	repo, err := m.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	if err := walkHistory(repo, head.Hash(), func(*object.Commit) error { n++; return nil }); err != nil {
+		t.Fatalf("walkHistory: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("walkHistory visited %d commits of a depth-2 clone, want 2", n)
+	}
+	if got, err := commitsBetween(repo, head.Hash(), head.Hash()); err != nil || got != 0 {
+		t.Errorf("commitsBetween(head, head) = %d, %v; want 0", got, err)
+	}
+}
+
+// 本地和远程一致时不需要下载，浅克隆同样报告为最新
+func TestStatusShallowUpToDate(t *testing.T) {
+	src := newFixtureRepo(t)
+	src.commit(map[string]string{"a.hl": "cmd a {}\n"})
+	src.commit(map[string]string{"b.hl": "cmd b {}\n"})
+
+	m := src.clone(t, 1)
+	result, err := m.Status(t.Context())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !result.UpToDate() || result.BehindBy != 0 {
+		t.Errorf("UpToDate() = %v, BehindBy = %d; want true, 0", result.UpToDate(), result.BehindBy)
+	}
+}
@@ -0,0 +1,121 @@
+package schemamanager
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RemoteTags 列出 RepoURL 上的标签名，按 SortTags 排序；只查询远程引用，不需要本地缓存
+func (m *Manager) RemoteTags(ctx context.Context) ([]string, error) {
+	refs, err := m.RemoteRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, ref := range refs {
+		if ref.Name().IsTag() && !strings.HasSuffix(ref.Name().String(), "^{}") {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+	SortTags(tags)
+	return tags, nil
+}
+
+// SortTags 把标签从旧到新排序：不是版本号的标签按名称排在前面，版本号（可以带 v 前缀，
+// 例如 v1.2.3、1.10、v2.0.0-rc.1）按语义化版本排在后面，所以最后一个是最新的版本
+func SortTags(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, aok := parseVersion(tags[i])
+		b, bok := parseVersion(tags[j])
+		switch {
+		case aok != bok:
+			return bok
+		case !aok:
+			return tags[i] < tags[j]
+		}
+		if c := compareVersions(a, b); c != 0 {
+			return c < 0
+		}
+		return tags[i] < tags[j]
+	})
+}
+
+type version struct {
+	nums []int
+	pre  []string
+}
+
+// 解析 [v]N(.N)*[-pre][+build]，构建信息不参与比较
+func parseVersion(tag string) (version, bool) {
+	s := strings.TrimPrefix(strings.TrimPrefix(tag, "v"), "V")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, hasPre := strings.Cut(s, "-")
+	var v version
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	if hasPre {
+		if pre == "" {
+			return version{}, false
+		}
+		v.pre = strings.Split(pre, ".")
+	}
+	return v, true
+}
+
+// 缺少的数字部分视为 0；预发布版本比正式版本旧，预发布标识逐段比较，数字段比字母段旧
+func compareVersions(a, b version) int {
+	for i := 0; i < max(len(a.nums), len(b.nums)); i++ {
+		var x, y int
+		if i < len(a.nums) {
+			x = a.nums[i]
+		}
+		if i < len(b.nums) {
+			y = b.nums[i]
+		}
+		if x != y {
+			return cmpInt(x, y)
+		}
+	}
+	switch {
+	case len(a.pre) == 0 && len(b.pre) == 0:
+		return 0
+	case len(a.pre) == 0:
+		return 1
+	case len(b.pre) == 0:
+		return -1
+	}
+	for i := 0; i < min(len(a.pre), len(b.pre)); i++ {
+		x, xerr := strconv.Atoi(a.pre[i])
+		y, yerr := strconv.Atoi(b.pre[i])
+		switch {
+		case xerr == nil && yerr == nil:
+			if x != y {
+				return cmpInt(x, y)
+			}
+		case xerr == nil:
+			return -1
+		case yerr == nil:
+			return 1
+		case a.pre[i] != b.pre[i]:
+			return strings.Compare(a.pre[i], b.pre[i])
+		}
+	}
+	return cmpInt(len(a.pre), len(b.pre))
+}
+
+func cmpInt(x, y int) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package schemamanager
+
+import (
+	"os"
+
+	"schema-manager/schemamanager/hl"
+)
+
+// ValidationResult 是一个 .hl 文件的解析结果，Err 为 nil 表示文件有效
+type ValidationResult struct {
+	Path string `json:"path"`
+	Err  error  `json:"-"`
+}
+
+// Validate 解析缓存中的所有 .hl 文件并返回每个文件的结果
+func (m *Manager) Validate() ([]ValidationResult, error) {
+	if !m.Exists() {
+		return nil, ErrNotInitialized
+	}
+	if err := m.requireWorktree("validate"); err != nil {
+		return nil, err
+	}
+
+	entries, err := m.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	return parallel(m.jobs(), len(entries), func(i int) (ValidationResult, error) {
+		return ValidationResult{Path: entries[i].relPath, Err: ValidateFile(entries[i].path)}, nil
+	})
+}
+
+// ValidateFile 解析单个 .hl 文件，返回语法错误或读取错误
+func ValidateFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = hl.Parse(f)
+	return err
+}
@@ -0,0 +1,346 @@
+package schemamanager
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// 遍历得到的一个 .hl 文件
+type entry struct {
+	path string
+	// relPath 是相对缓存目录、以 / 分隔的路径
+	relPath string
+	d       fs.DirEntry
+}
+
+// 按 Dir、Exclude、.hlignore 和大小范围收集缓存中的 .hl 文件
+func (m *Manager) collect() ([]entry, error) {
+	root, err := m.walkRoot()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := m.ignoreRules()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := m.walk(root, m.Exclude, rules, m.maxDepth())
+	if err != nil || (m.MinSize == nil && m.MaxSize == nil) {
+		return entries, err
+	}
+
+	// 大小过滤需要读取文件信息，读取失败的文件同样跳过
+	kept := entries[:0]
+	for _, e := range entries {
+		info, err := e.d.Info()
+		if err != nil {
+			m.warnf("Warning: skipping %s: %v\n", e.relPath, err)
+			continue
+		}
+		if m.sizeOK(info.Size()) {
+			kept = append(kept, e)
+		}
+	}
+	return kept, nil
+}
+
+// 报告 size 是否在 MinSize 和 MaxSize 的范围内
+func (m *Manager) sizeOK(size int64) bool {
+	return (m.MinSize == nil || size >= *m.MinSize) && (m.MaxSize == nil || size <= *m.MaxSize)
+}
+
+// 收集 root 下未被 exclude 排除、未被 ignore 忽略、深度不超过 maxDepth 的 .hl 文件，maxDepth 小于 0 时不限制；
+// WalkDir 按词法顺序遍历，结果天然按路径排序
+func (m *Manager) walk(root string, exclude []string, ignore ignoreRules, maxDepth int) ([]entry, error) {
+	var entries []entry
+	err := m.visit(root, exclude, ignore, maxDepth, func(e entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// 和 walk 相同的遍历，每找到一个文件就调用 fn；fn 返回错误时停止遍历并返回该错误
+func (m *Manager) visit(root string, exclude []string, ignore ignoreRules, maxDepth int, fn func(entry) error) error {
+	// 先检查排除模式，避免遍历中途才报错
+	if err := checkExclude(exclude); err != nil {
+		return err
+	}
+	// 起点本身位于被忽略的目录中时没有文件
+	if rel, _ := filepath.Rel(m.CacheDir, root); ignore.dirIgnored(rel) {
+		return nil
+	}
+
+	var fnErr error
+	cacheReal, _ := filepath.EvalSymlinks(m.CacheDir)
+	rootReal, _ := filepath.EvalSymlinks(root)
+
+	// 遍历磁盘上的 dir，它在缓存中的逻辑位置是 logical，二者只在进入符号链接目录后不同；
+	// open 是当前链路上经过的链接所在目录的真实路径，链接指向其中任一目录或其上级时就会成环
+	var walkTree func(dir, logical string, open []string) error
+	walkTree = func(dir, logical string, open []string) error {
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			rel, _ := filepath.Rel(dir, path)
+			lpath := filepath.Join(logical, rel)
+			// 对外的相对路径在所有平台上都以 / 分隔
+			relPath, _ := filepath.Rel(m.CacheDir, lpath)
+			relPath = filepath.ToSlash(relPath)
+
+			// 无法读取的条目跳过并给出警告，不中断整个遍历
+			if err != nil {
+				if lpath == root {
+					return err
+				}
+				m.warnf("Warning: skipping %s: %v\n", relPath, err)
+				return nil
+			}
+
+			// 指向目录的符号链接按目录处理，但不能用 SkipDir 跳过
+			isDir := d.IsDir()
+			var target string
+			if m.FollowSymlinks && d.Type()&fs.ModeSymlink != 0 {
+				if real, err := filepath.EvalSymlinks(path); err == nil {
+					if info, err := os.Stat(real); err == nil && info.IsDir() {
+						isDir, target = true, real
+					}
+				}
+			}
+			skip := func() error {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// 默认跳过 .git 等隐藏目录和隐藏文件
+			if lpath != root && !m.IncludeHidden && strings.HasPrefix(d.Name(), ".") {
+				return skip()
+			}
+
+			// 被排除的目录不再深入
+			if lpath != root && excluded(m.CacheDir, exclude, lpath, d.Name()) {
+				return skip()
+			}
+
+			// 超过最大深度的目录不再深入，起点本身超过时同样没有文件
+			if isDir && maxDepth >= 0 && depth(relPath) > maxDepth {
+				return skip()
+			}
+			if lpath != root && ignore.match(relPath, isDir) {
+				return skip()
+			}
+
+			if target != "" {
+				// 只跟随缓存内的链接，和 Resolve 的限制一致
+				if !within(cacheReal, target) {
+					m.warnf("Warning: skipping %s: symlink points outside the cache\n", relPath)
+					return nil
+				}
+				parent, _ := filepath.EvalSymlinks(filepath.Dir(path))
+				chain := append(open[:len(open):len(open)], parent)
+				for _, dir := range chain {
+					if within(target, dir) {
+						m.debugf("skip %s: symlink cycle through %s\n", relPath, target)
+						return nil
+					}
+				}
+				return walkTree(target, lpath, chain)
+			}
+
+			if !isDir && m.IsSchemaFile(d.Name()) {
+				m.debugf("visit %s\n", relPath)
+				if err := fn(entry{path: path, relPath: relPath, d: d}); err != nil {
+					fnErr = err
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	// 起点本身是符号链接（例如 Dir 指向链接）时 WalkDir 不会进入，从链接的目标开始遍历
+	start := root
+	if rootReal != "" {
+		start = rootReal
+	}
+	if err := walkTree(start, root, []string{rootReal}); err != nil {
+		if fnErr != nil {
+			return fnErr
+		}
+		return fmt.Errorf("walking directory: %w", err)
+	}
+	return nil
+}
+
+// 目录 relPath 相对缓存根目录的层数，其中的文件深度与之相同；根目录为 0
+func depth(relPath string) int {
+	if relPath == "." || relPath == "" {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(relPath), "/") + 1
+}
+
+func (m *Manager) maxDepth() int {
+	if m.MaxDepth == nil {
+		return -1
+	}
+	return *m.MaxDepth
+}
+
+// DefaultExtension 是 schema 文件默认的扩展名
+const DefaultExtension = ".hl"
+
+// 规范化后的扩展名列表，都以 . 开头；未设置时只有 .hl
+func (m *Manager) extensions() []string {
+	if len(m.Extensions) == 0 {
+		return []string{DefaultExtension}
+	}
+	exts := make([]string, 0, len(m.Extensions))
+	for _, ext := range m.Extensions {
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// IsSchemaFile 报告 name 是否带有要处理的扩展名
+func (m *Manager) IsSchemaFile(name string) bool {
+	for _, ext := range m.extensions() {
+		if ext != "" && strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaExt 返回 name 带有的要处理的扩展名，多个扩展名都匹配时取最长的，例如 .schema.hl 优先于 .hl；不匹配时为空
+func (m *Manager) SchemaExt(name string) string {
+	match := ""
+	for _, ext := range m.extensions() {
+		if ext != "" && strings.HasSuffix(name, ext) && len(ext) > len(match) {
+			match = ext
+		}
+	}
+	return match
+}
+
+// 遍历的起点：缓存目录或其中的 Dir 子目录
+func (m *Manager) walkRoot() (string, error) {
+	if m.Dir == "" {
+		return m.CacheDir, nil
+	}
+	root, err := m.Resolve(m.Dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(root)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("directory %q not found in cache", m.Dir)
+	}
+	return root, nil
+}
+
+func checkExclude(exclude []string) error {
+	for _, pattern := range exclude {
+		if _, err := pathpkg.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// 报告 root 下的 path 是否匹配任一排除模式
+func excluded(root string, exclude []string, path, name string) bool {
+	if len(exclude) == 0 {
+		return false
+	}
+	relPath, _ := filepath.Rel(root, path)
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range exclude {
+		subject := name
+		if strings.Contains(pattern, "/") {
+			subject = relPath
+		}
+		if ok, _ := pathpkg.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 并行读取文件信息
+func (m *Manager) stat(entries []entry) ([]File, error) {
+	return parallel(m.jobs(), len(entries), func(i int) (File, error) {
+		info, err := entries[i].d.Info()
+		if err != nil {
+			return File{}, err
+		}
+		return File{Path: entries[i].relPath, Size: info.Size(), ModTime: info.ModTime()}, nil
+	})
+}
+
+func (m *Manager) jobs() int {
+	if m.Jobs > 0 {
+		return m.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// 用 jobs 个协程对 0..n-1 执行 fn，结果按下标顺序返回；任一调用出错后不再分发新任务并返回该错误，
+// 此时已分发的任务都会执行完，结果中保留它们的返回值
+func parallel[T any](jobs, n int, fn func(i int) (T, error)) ([]T, error) {
+	results := make([]T, n)
+	if jobs > n {
+		jobs = n
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		next     int
+	)
+
+	// 取下一个任务下标，出错后返回 -1 让协程退出
+	take := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr != nil || next >= n {
+			return -1
+		}
+		i := next
+		next++
+		return i
+	}
+
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := take(); i >= 0; i = take() {
+				r, err := fn(i)
+				results[i] = r
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return results, firstErr
+	}
+	return results, nil
+}
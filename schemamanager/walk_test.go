@@ -0,0 +1,120 @@
+package schemamanager
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// 在普通目录中创建嵌套的缓存：a/b/c.hl、a/d.hl 和 top.hl
+func nestedCache(t *testing.T) *Manager {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "cache")
+	for _, name := range []string{"a/b/c.hl", "a/d.hl", "top.hl"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("cmd "+filepath.Base(name)+" {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(dir)
+}
+
+// 在缓存中创建指向 target 的目录符号链接 name，平台不支持时跳过
+func symlinkDir(t *testing.T, m *Manager, target, name string) {
+	t.Helper()
+	if err := os.Symlink(target, filepath.Join(m.CacheDir, name)); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+}
+
+func listPaths(t *testing.T, m *Manager) []string {
+	t.Helper()
+	files, err := m.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func searchPaths(t *testing.T, m *Manager, pattern string, opts SearchOptions) []string {
+	t.Helper()
+	matches, err := m.Search(pattern, opts)
+	if err != nil {
+		t.Fatalf("Search(%q): %v", pattern, err)
+	}
+	var paths []string
+	for _, match := range matches {
+		paths = append(paths, match.Path)
+	}
+	return paths
+}
+
+func checkPaths(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("%s = %q, want %q", what, got, want)
+	}
+}
+
+// 路径相对缓存根目录并以 / 分隔，和平台的分隔符无关
+func TestListNestedPaths(t *testing.T) {
+	m := nestedCache(t)
+	checkPaths(t, "List", listPaths(t, m), []string{"a/b/c.hl", "a/d.hl", "top.hl"})
+	checkPaths(t, "Search by name", searchPaths(t, m, "c", SearchOptions{}), []string{"a/b/c.hl"})
+	checkPaths(t, "Search full path", searchPaths(t, m, "^a/b/", SearchOptions{FullPath: true}), []string{"a/b/c.hl"})
+	checkPaths(t, "Search content", searchPaths(t, m, "cmd c.hl", SearchOptions{Content: true, Fixed: true}), []string{"a/b/c.hl"})
+}
+
+// Dir 只改变遍历的起点，路径仍然相对缓存根目录
+func TestListDir(t *testing.T) {
+	m := nestedCache(t)
+	m.Dir = "a"
+	checkPaths(t, "List with Dir a", listPaths(t, m), []string{"a/b/c.hl", "a/d.hl"})
+	m.Dir = "a/b"
+	checkPaths(t, "List with Dir a/b", listPaths(t, m), []string{"a/b/c.hl"})
+	checkPaths(t, "Search with Dir a/b", searchPaths(t, m, ".", SearchOptions{}), []string{"a/b/c.hl"})
+
+	m.Dir = "missing"
+	if _, err := m.List(); err == nil {
+		t.Error("List with a missing Dir succeeded")
+	}
+}
+
+// 跟随符号链接时，链接下的文件使用链接所在的逻辑路径
+func TestListFollowSymlinks(t *testing.T) {
+	m := nestedCache(t)
+	symlinkDir(t, m, filepath.Join("a", "b"), "link")
+
+	checkPaths(t, "List without FollowSymlinks", listPaths(t, m), []string{"a/b/c.hl", "a/d.hl", "top.hl"})
+
+	m.FollowSymlinks = true
+	checkPaths(t, "List with FollowSymlinks", listPaths(t, m), []string{"a/b/c.hl", "a/d.hl", "link/c.hl", "top.hl"})
+	checkPaths(t, "Search with FollowSymlinks", searchPaths(t, m, "c", SearchOptions{}), []string{"a/b/c.hl", "link/c.hl"})
+
+	// Dir 指向链接时从链接的目标开始遍历，路径仍然位于链接之下
+	m.Dir = "link"
+	checkPaths(t, "List with Dir link", listPaths(t, m), []string{"link/c.hl"})
+}
+
+// 指向缓存外的链接和指回上级目录的链接都不会被跟随
+func TestListSymlinkOutsideAndCycle(t *testing.T) {
+	m := nestedCache(t)
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "x.hl"), []byte("cmd x {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	symlinkDir(t, m, outside, "outside")
+	symlinkDir(t, m, "..", filepath.Join("a", "up"))
+
+	m.FollowSymlinks = true
+	m.Warnings = nil
+	checkPaths(t, "List", listPaths(t, m), []string{"a/b/c.hl", "a/d.hl", "top.hl"})
+}
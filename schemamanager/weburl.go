@@ -0,0 +1,80 @@
+package schemamanager
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WebURL 返回缓存中 relPath 在托管网站上的地址，形如 https://github.com/owner/repo/blob/<commit>/<path>。
+// 地址由 origin 和 HEAD 的提交构造，固定的提交或分离 HEAD 时同样指向当前检出的内容；
+// SSH 形式的 origin，例如 git@github.com:owner/repo.git，会转换为对应的 https 地址
+func (m *Manager) WebURL(relPath string) (string, error) {
+	repo, err := m.open()
+	if err != nil {
+		return "", err
+	}
+	path, err := m.Resolve(relPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+
+	origin := originURL(repo)
+	base, err := webBase(origin)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("getting HEAD: %w", err)
+	}
+
+	// 路径每一段分别转义，保留分隔的 /
+	segments := strings.Split(strings.Trim(filepath.ToSlash(relPath), "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return fmt.Sprintf("%s/blob/%s/%s", base, head.Hash(), strings.Join(segments, "/")), nil
+}
+
+// 把 origin 地址转换为仓库网页的地址，去掉用户信息和 .git 后缀
+func webBase(origin string) (string, error) {
+	var host, path string
+	switch {
+	case strings.HasPrefix(origin, "http://"), strings.HasPrefix(origin, "https://"), strings.HasPrefix(origin, "ssh://"):
+		u, err := url.Parse(origin)
+		if err != nil {
+			return "", fmt.Errorf("parsing origin %q: %w", redactURL(origin), err)
+		}
+		scheme := u.Scheme
+		if scheme == "ssh" {
+			scheme = "https"
+		}
+		host, path = scheme+"://"+u.Hostname(), u.Path
+		// ssh 的端口不是网页的端口，只保留 http(s) 的端口
+		if u.Port() != "" && u.Scheme != "ssh" {
+			host += ":" + u.Port()
+		}
+	case strings.Contains(origin, ":") && !strings.Contains(origin, "://"):
+		// scp 形式 [user@]host:owner/repo.git；单个字母的是 Windows 盘符
+		hostPart, p, _ := strings.Cut(origin, ":")
+		if len(hostPart) < 2 {
+			break
+		}
+		if i := strings.LastIndex(hostPart, "@"); i >= 0 {
+			hostPart = hostPart[i+1:]
+		}
+		host, path = "https://"+hostPart, p
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return "", fmt.Errorf("origin %q is not hosted on a website", redactURL(origin))
+	}
+	return host + "/" + path, nil
+}
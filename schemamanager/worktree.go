@@ -0,0 +1,59 @@
+package schemamanager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// LocalChange 是缓存工作区中一个未提交的修改
+type LocalChange struct {
+	Path string `json:"path"`
+	// Status 是 modified、added、deleted、renamed、copied、unmerged 或 untracked
+	Status string `json:"status"`
+}
+
+var statusNames = map[git.StatusCode]string{
+	git.Modified:           "modified",
+	git.Added:              "added",
+	git.Deleted:            "deleted",
+	git.Renamed:            "renamed",
+	git.Copied:             "copied",
+	git.UpdatedButUnmerged: "unmerged",
+	git.Untracked:          "untracked",
+}
+
+// LocalChanges 返回缓存工作区中未提交的修改和未跟踪的文件，按路径排序；init -f 会丢弃这些内容
+func (m *Manager) LocalChanges() ([]LocalChange, error) {
+	repo, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	// 裸仓库和没有检出的缓存没有工作区中的文件，也就没有本地修改
+	if m.IsBare() || noCheckout(repo) {
+		return nil, nil
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("getting worktree: %w", err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		return nil, fmt.Errorf("getting worktree status: %w", err)
+	}
+
+	var changes []LocalChange
+	for path, fs := range status {
+		// 工作区的状态优先于暂存区
+		code := fs.Worktree
+		if code == git.Unmodified {
+			code = fs.Staging
+		}
+		if name, ok := statusNames[code]; ok {
+			changes = append(changes, LocalChange{Path: path, Status: name})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"schema-manager/schemamanager"
+
+	"github.com/spf13/cobra"
+)
+
+// serve 监听的地址
+var serveAddr string
+
+// 收到中断信号后等待正在处理的请求完成的最长时间
+const shutdownTimeout = 5 * time.Second
+
+func newServeCmd() *cobra.Command {
+	var serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the cached .hl files over HTTP",
+		Long: `Start an HTTP server that exposes the cache as a lightweight schema registry:
+
+  GET /list          JSON array of the .hl files, as 'list -o json'
+  GET /file/<path>   raw content of a .hl file, <path> as printed by list
+  GET /status        JSON sync status, as 'status -o json'
+
+Only .hl files can be fetched; paths that escape the cache or point into hidden
+directories such as .git are rejected. The remote ref for /status is cached
+for --max-age. Each request holds the cache lock like the matching command and
+answers 503 when another process keeps it longer than --lock-timeout. Press Ctrl-C to stop; requests in flight are allowed to finish.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serveCache()
+		},
+	}
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Address to listen on, host:port (port 0 picks a free port)")
+	serveCmd.Flags().DurationVar(&maxAge, "max-age", 5*time.Minute, "Reuse the remote ref looked up within this duration for /status (0 always queries)")
+	return serveCmd
+}
+
+func serveCache() error {
+	if !newManager().Exists() {
+		return schemamanager.ErrNotInitialized
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /list", serveList)
+	mux.HandleFunc("GET /file/{path...}", serveFile)
+	mux.HandleFunc("GET /status", serveStatus)
+
+	listener, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: logRequests(mux), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(listener) }()
+	infof("Serving %s on http://%s\n", cacheDir, listener.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	infoln("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// -v 时把每个请求记录到 stderr
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		debugf(1, "%s %s\n", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 和对应的 CLI 命令一样，每个请求在处理期间持有缓存锁：读文件用共享锁，/status 会拉取远程并写入缓存，用排他锁。
+// 拿不到锁时已经写好错误响应，返回 nil
+func lockCache(w http.ResponseWriter, m *schemamanager.Manager, exclusive bool) func() error {
+	release, err := m.Lock(exclusive, lockTimeout)
+	if err != nil {
+		httpError(w, err)
+		return nil
+	}
+	return release
+}
+
+func serveList(w http.ResponseWriter, r *http.Request) {
+	m := newManager()
+	release := lockCache(w, m, false)
+	if release == nil {
+		return
+	}
+	defer release()
+	files, err := m.List()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if files == nil {
+		files = []schemamanager.File{}
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+func serveFile(w http.ResponseWriter, r *http.Request) {
+	m := newManager()
+	rel := r.PathValue("path")
+	// 只提供 schema 文件，.git 中的配置可能含有凭据
+	clean := path.Clean("/" + rel)[1:]
+	if clean != rel || !m.IsSchemaFile(path.Base(rel)) || (!hidden && hiddenSegment(rel)) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("no .hl file %q in the cache", rel)})
+		return
+	}
+	release := lockCache(w, m, false)
+	if release == nil {
+		return
+	}
+	defer release()
+	data, err := m.ReadFile(rel)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(data)
+}
+
+func serveStatus(w http.ResponseWriter, r *http.Request) {
+	if localDir != "" {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "version control information is not available for a local directory"})
+		return
+	}
+	ctx, cancel := networkContext()
+	defer cancel()
+	m := newManager()
+	m.RemoteMaxAge = maxAge
+	release := lockCache(w, m, true)
+	if release == nil {
+		return
+	}
+	defer release()
+	result, err := m.Status(ctx)
+	if err != nil {
+		httpError(w, timeoutError(ctx, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusJSON(result))
+}
+
+// 路径中任一段以 . 开头
+func hiddenSegment(p string) bool {
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// 按错误类型选择状态码，响应体是 {"error": "..."}；文件不存在时不暴露缓存的绝对路径
+func httpError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		status, err = http.StatusNotFound, errors.New("not found")
+	case errors.Is(err, schemamanager.ErrNotInitialized), errors.Is(err, schemamanager.ErrCorruptCache), errors.Is(err, schemamanager.ErrLocked):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, schemamanager.ErrRemoteUnavailable):
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// --min-size、--max-size 和 --empty 解析后的大小范围，nil 表示不限制
+var sizeLimits struct {
+	min, max *int64
+}
+
+// 解析大小过滤参数，下限大于上限时报错
+func resolveSizes() error {
+	sizeLimits.min, sizeLimits.max = nil, nil
+	if minSize != "" {
+		n, err := parseSize(minSize)
+		if err != nil {
+			return fmt.Errorf("invalid --min-size: %w", err)
+		}
+		sizeLimits.min = &n
+	}
+	if maxSize != "" {
+		n, err := parseSize(maxSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
+		sizeLimits.max = &n
+	}
+	if emptyOnly {
+		zero := int64(0)
+		sizeLimits.max = &zero
+	}
+	if sizeLimits.min != nil && sizeLimits.max != nil && *sizeLimits.min > *sizeLimits.max {
+		return fmt.Errorf("--min-size %s is larger than the maximum size", formatBytes(*sizeLimits.min))
+	}
+	return nil
+}
+
+// 解析 512、10k、1.5M 这样的大小，单位按 1024 进位，可以写成 k、KB 或 KiB
+func parseSize(s string) (int64, error) {
+	num := strings.ToLower(strings.TrimSpace(s))
+	if strings.HasSuffix(num, "ib") {
+		num = strings.TrimSuffix(num, "ib")
+	} else {
+		num = strings.TrimSuffix(num, "b")
+	}
+	unit := 1.0
+	if i := strings.IndexAny(num, "kmgt"); i >= 0 && i == len(num)-1 {
+		unit = math.Pow(1024, float64(strings.IndexByte("kmgt", num[i])+1))
+		num = num[:i]
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || v < 0 || math.IsNaN(v) || v*unit > math.MaxInt64 {
+		return 0, fmt.Errorf("%q is not a size; use a number with an optional k, M or G suffix", s)
+	}
+	return int64(v * unit), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"schema-manager/schemamanager"
+
+	"github.com/spf13/cobra"
+)
+
+func newSyncCmd() *cobra.Command {
+	var syncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Clone the cache if it is missing, otherwise pull the latest changes",
+		Long: `Bring the cache up to date with a single command that is safe to run
+repeatedly, for automation: when the cache does not exist it is cloned as with
+'init', and when it exists the latest changes are pulled as with 'update'. The
+existing cache and its history are never removed. The first line of output
+says which action was taken.
+
+sync never prompts. A cache left corrupt by an interrupted clone is an error
+unless --repair is given. --branch, --depth, --path, --bare and --no-checkout only apply when
+the cache is cloned; an existing cache keeps following the branch it was
+cloned with. The exit codes are those of init and update.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return syncRepository()
+		},
+	}
+	syncCmd.Flags().StringVarP(&branch, "branch", "b", "", "When cloning, clone this branch or tag instead of the default branch")
+	syncCmd.Flags().IntVar(&depth, "depth", 0, "When cloning, create a shallow clone truncated to the given number of commits")
+	syncCmd.Flags().StringArrayVar(&sparseDirs, "path", nil, "When cloning, only check out this directory of the repository; repeatable")
+	syncCmd.Flags().BoolVar(&bareClone, "bare", false, "When cloning, clone without a working tree")
+	syncCmd.Flags().BoolVar(&repair, "repair", false, "Remove and re-clone a cache directory left corrupt by an interrupted clone")
+	syncCmd.Flags().BoolVar(&noCheckout, "no-checkout", false, "When cloning, do not check out any files")
+	syncCmd.MarkFlagsMutuallyExclusive("path", "bare", "no-checkout")
+	annotate(lockAnnotation, lockExclusive, syncCmd)
+	return syncCmd
+}
+
+// init 和 update 合在一起：缓存不存在时克隆，存在时快进拉取，不询问也不删除已有的缓存
+func syncRepository() error {
+	if err := requireGit("sync"); err != nil {
+		return err
+	}
+	switch newManager().State() {
+	case schemamanager.CacheMissing:
+		infoln("Cache not found; cloning.")
+		return initRepository()
+	case schemamanager.CacheBroken:
+		if !repair {
+			return fmt.Errorf("cache directory %s is corrupt; re-run with --repair to remove it and clone again", cacheDir)
+		}
+		// --repair 时 init 不会询问，直接删除后重新克隆
+		infoln("Cache is corrupt; cloning again.")
+		return initRepository()
+	}
+	infoln("Cache found; pulling.")
+	return updateRepository()
+}
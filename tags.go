@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newTagsCmd() *cobra.Command {
+	var latest bool
+	var tagsCmd = &cobra.Command{
+		Use:   "tags",
+		Short: "List the tags available on the remote repository",
+		Long: `List the tags of the configured repository by querying the remote, without
+needing a local cache. Version tags (v1.2.3, 1.10, v2.0.0-rc.1) are sorted by
+semantic version, oldest first, after any other tags; --latest prints only the
+newest one, e.g. to pass to init --branch or pin. The exit code is 3 when the
+repository has no tags.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listTags(latest)
+		},
+	}
+	tagsCmd.Flags().BoolVar(&latest, "latest", false, "Print only the newest tag")
+	return tagsCmd
+}
+
+func listTags(latest bool) error {
+	ctx, cancel := networkContext()
+	defer cancel()
+	tags, err := newManager().RemoteTags(ctx)
+	if err != nil {
+		return timeoutError(ctx, err)
+	}
+	if len(tags) == 0 {
+		infof("No tags found on %s.\n", repoURL)
+		return &exitError{code: exitNoMatches}
+	}
+	if latest {
+		tags = tags[len(tags)-1:]
+	}
+
+	if structured() {
+		if latest {
+			return writeStructured(tags[0])
+		}
+		return writeStructured(tags)
+	}
+	for _, t := range tags {
+		fmt.Println(t)
+	}
+	return nil
+}
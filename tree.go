@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 目录树节点，叶子节点是 .hl 文件
+type treeNode struct {
+	name     string
+	children map[string]*treeNode
+}
+
+// 根据相对路径构建目录树；只有包含 .hl 文件的目录才会出现
+func buildTree(paths []string) *treeNode {
+	root := &treeNode{name: ".", children: map[string]*treeNode{}}
+	for _, p := range paths {
+		node := root
+		for _, part := range strings.Split(filepath.ToSlash(p), "/") {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{name: part, children: map[string]*treeNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+func printTree(w io.Writer, root *treeNode) {
+	fmt.Fprintln(w, root.name)
+	printTreeChildren(w, root, "")
+}
+
+func printTreeChildren(w io.Writer, node *treeNode, prefix string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		connector, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			connector, indent = "└── ", "    "
+		}
+		// 有子节点的是目录
+		label := paint(ansiCyan, name)
+		if len(node.children[name].children) > 0 {
+			label = paint(ansiBlue, name)
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, connector, label)
+		printTreeChildren(w, node.children[name], prefix+indent)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// 构建信息，发布时通过 -ldflags 注入，例如
+// go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// version --output json 的输出结构
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoGit     string `json:"goGit"`
+	Go        string `json:"go"`
+	Platform  string `json:"platform"`
+}
+
+// 汇总构建信息，没有注入的字段从 go 记录的构建信息中补充（构建时间退而使用提交时间）
+func buildInfo() versionInfo {
+	info := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoGit:     "unknown",
+		Go:        runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range bi.Deps {
+			if dep.Path == "github.com/go-git/go-git/v6" {
+				info.GoGit = dep.Version
+			}
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+func (v versionInfo) String() string {
+	return fmt.Sprintf("schema-manager %s\n  commit:     %s\n  built:      %s\n  go-git:     %s\n  go:         %s %s\n",
+		v.Version, v.Commit, v.BuildDate, v.GoGit, v.Go, v.Platform)
+}
+
+func printVersion() error {
+	info := buildInfo()
+	if outputFmt == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	fmt.Print(info)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 连续的文件事件合并成一次重新执行
+const watchDebounce = 200 * time.Millisecond
+
+// 执行 run，之后每当缓存目录中的 schema 文件变化时清屏重新执行，直到按下 Ctrl-C
+func watchCache(run func() error) error {
+	// 工作区的修改不会改变 HEAD，索引会过期，监视时总是直接遍历
+	noIndex = true
+	m := newManager()
+	if m.IsBare() || m.IsNoCheckout() {
+		return errors.New("--watch is not available for a bare or no-checkout cache")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("starting file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watchTree(watcher, cacheDir); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rerun := func() {
+		if isTerminal(os.Stdout) {
+			fmt.Print("\x1b[H\x1b[2J")
+		}
+		// 没有匹配等退出码在监视模式下没有意义，只报告真正的错误
+		var exitErr *exitError
+		if err := run(); err != nil && !(errors.As(err, &exitErr) && exitErr.err == nil) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "Watching %s for changes (Ctrl-C to stop)...\n", cacheDir)
+	}
+	rerun()
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// 新建的目录也需要监视
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watchTree(watcher, event.Name)
+					timer.Reset(watchDebounce)
+					continue
+				}
+			}
+			if m.IsSchemaFile(event.Name) {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			debugf(1, "watch error: %v\n", err)
+		case <-timer.C:
+			rerun()
+		}
+	}
+}
+
+// 监视 root 下的所有目录，默认跳过 .git 等隐藏目录
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && !hidden && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("watching %s: %w", path, err)
+		}
+		return nil
+	})
+}